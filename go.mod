@@ -4,4 +4,10 @@ go 1.26.2
 
 require github.com/sethvargo/go-envconfig v1.3.0
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/term v0.46.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.48.0 // indirect
@@ -1,11 +1,20 @@
 package app
 
 import (
+	"bufio"
+	"cmp"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
+	"github.com/griggsjared/tm/internal/cli"
+	"github.com/griggsjared/tm/internal/fzf"
+	"github.com/griggsjared/tm/internal/history"
 	"github.com/griggsjared/tm/internal/session"
+	"github.com/griggsjared/tm/internal/tmux"
 )
 
 type TmuxClient interface {
@@ -14,9 +23,17 @@ type TmuxClient interface {
 	Path() string
 	Version() string
 	NewSession(s *session.Session) error
+	KillSession(s *session.Session) error
+	RenameSession(s *session.Session, name string) error
 	AttachSession(s *session.Session) error
 	SwitchSession(s *session.Session) error
+	AddHook(s *session.Session, event, command string) error
 	CurrentSession() string
+	ListWindows(s *session.Session) (string, error)
+	CapturePane(s *session.Session) (string, error)
+	Socket() tmux.Socket
+	NewWindow(s *session.Session, name, dir string) error
+	DisplayPopup(width, height, border, dir, command string) error
 }
 
 type SessionFinder interface {
@@ -29,7 +46,44 @@ type FzfClient interface {
 	IsAvailable() bool
 	Path() string
 	Version() string
-	Select(items []string, query string) (int, bool, error)
+	Select(items []string, query string, opts fzf.Options) (fzf.Selection, bool, error)
+	SelectMulti(items []string, query string, opts fzf.Options) ([]int, bool, error)
+}
+
+// Picker lets the user choose from a list, either through fzf or the
+// built-in picker.
+type Picker interface {
+	IsAvailable() bool
+	Select(items []string, query string, opts fzf.Options) (fzf.Selection, bool, error)
+	SelectMulti(items []string, query string, opts fzf.Options) ([]int, bool, error)
+}
+
+type HookRunner interface {
+	Run(event string, h session.Hook, s *session.Session) error
+	Script(event string, h session.Hook, s *session.Session) string
+}
+
+type TrustStore interface {
+	Lookup(path, hash string) (trusted bool, ok bool)
+	Save(path, hash string, trusted bool) error
+}
+
+type HistoryStore interface {
+	Record(name, dir string) error
+	Rename(oldName, newName string) error
+	Scores() map[string]float64
+	Entries() ([]history.Entry, error)
+	Prune(remove func(history.Entry) bool) ([]history.Entry, error)
+}
+
+type ConfigFile interface {
+	Path() string
+	SessionFiles(name string) ([]string, error)
+	RenameSession(oldName, newName string) error
+}
+
+type Previewer interface {
+	Project(w io.Writer, dir string) error
 }
 
 type App struct {
@@ -37,27 +91,59 @@ type App struct {
 	debug         bool
 	tmuxClient    TmuxClient
 	fzfClient     FzfClient
+	picker        Picker
 	sessionFinder SessionFinder
+	hookRunner    HookRunner
+	trustStore    TrustStore
+	historyStore  HistoryStore
+	configFile    ConfigFile
+	previewer     Previewer
+	matcher       Matcher
+	popup         Popup
+	cacheDir      string
+	stdin         *bufio.Reader
+}
+
+// Options are the settings of an App, as opposed to the clients it talks
+// to. An empty CacheDir puts cache files in the system temporary directory.
+type Options struct {
+	Matcher  Matcher
+	Popup    Popup
+	CacheDir string
+	Debug    bool
+	Version  string
 }
 
-func New(tc TmuxClient, fc FzfClient, ss SessionFinder, debug bool, version string) *App {
+func New(tc TmuxClient, fc FzfClient, pk Picker, ss SessionFinder, hr HookRunner, ts TrustStore, hs HistoryStore, cf ConfigFile, pv Previewer, opts Options) *App {
 	return &App{
-		version:       version,
-		debug:         debug,
+		version:       opts.Version,
+		debug:         opts.Debug,
 		tmuxClient:    tc,
 		fzfClient:     fc,
+		picker:        pk,
 		sessionFinder: ss,
+		hookRunner:    hr,
+		trustStore:    ts,
+		historyStore:  hs,
+		configFile:    cf,
+		previewer:     pv,
+		matcher:       opts.Matcher,
+		popup:         opts.Popup,
+		cacheDir:      opts.CacheDir,
 	}
 }
 
-func (a *App) Run(query string) int {
-	if query == "version" {
+func (a *App) Run(cmd cli.Command) int {
+	switch cmd.Name {
+	case "version":
 		fmt.Println("tm version", a.version)
 		return 0
-	}
-
-	if query == "status" {
+	case "status":
 		return a.runStatus()
+	case "history":
+		return a.runHistory(cmd.Args)
+	case "preview":
+		return a.runPreview(cmd.String("list"), firstArg(cmd.Args))
 	}
 
 	if !a.tmuxClient.IsAvailable() {
@@ -67,21 +153,45 @@ func (a *App) Run(query string) int {
 
 	currentSession := a.currentSession()
 
-	if query == "" {
-		if err := a.runInteractive(currentSession); err != nil {
-			fmt.Fprintln(os.Stderr, "Error:", err)
-			return 1
+	var err error
+	switch cmd.Name {
+	case "ls":
+		err = a.printSessionList(!cmd.Bool("all"), cmd.String("format"), cmd.String("source"))
+	case "kill":
+		err = a.runKill(firstArg(cmd.Args), currentSession, cmd.Bool("all-but-current"))
+	case "rename":
+		err = a.runRename(cmd.Args)
+	case "popup":
+		err = a.runPopup(firstArg(cmd.Args), currentSession)
+	default:
+		query := firstArg(cmd.Args)
+		if query == "" && a.popup.Always && a.tmuxClient.InsideTmux() && !insidePopup() {
+			err = a.openPopup("")
+		} else {
+			err = a.runOpen(query, currentSession)
 		}
-		return 0
 	}
-
-	if err := a.runWithQuery(query, currentSession); err != nil {
+	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
 		return 1
 	}
 	return 0
 }
 
+func (a *App) runOpen(query, currentSession string) error {
+	if query != "" {
+		return a.runWithQuery(query, currentSession)
+	}
+	return a.runInteractive(currentSession)
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
 func (a *App) currentSession() string {
 	if a.tmuxClient.InsideTmux() {
 		return a.tmuxClient.CurrentSession()
@@ -118,72 +228,297 @@ func (a *App) runStatus() int {
 	return exitCode
 }
 
+// runHistory prints the attach history, highest ranked first, or prunes it.
+// Pruning without names removes the sessions whose directory is gone.
+func (a *App) runHistory(args []string) int {
+	if len(args) == 0 {
+		entries, err := a.historyStore.Entries()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		for _, e := range entries {
+			fmt.Printf("%8.1f %6.1f  %s  %s [%s]\n", e.Score, e.Count, e.LastAccess.Format("2006-01-02 15:04"), e.Name, e.Dir)
+		}
+		return 0
+	}
+
+	if args[0] != "prune" {
+		fmt.Fprintf(os.Stderr, "Error: unknown history command %q\n", args[0])
+		return 1
+	}
+
+	names := args[1:]
+	removed, err := a.historyStore.Prune(func(e history.Entry) bool {
+		if len(names) > 0 {
+			return slices.Contains(names, e.Name)
+		}
+		return !dirExists(e.Dir)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	for _, e := range removed {
+		fmt.Printf("Removed %s [%s]\n", e.Name, e.Dir)
+	}
+	return 0
+}
+
 func (a *App) runInteractive(currentSession string) error {
-	sessions := a.sessionFinder.ListExcluding(false, currentSession)
-	selected, err := a.selectSession(sessions, "")
+	return a.pick(func() []*session.Session {
+		return a.rank(a.sessionFinder.ListExcluding(false, currentSession))
+	}, "")
+}
+
+func (a *App) runWithQuery(query, currentSession string) error {
+	// Try exact match first
+	s, err := a.sessionFinder.Find(query)
 	if err != nil {
+		return fmt.Errorf("error finding session: %w", err)
+	}
+	if s != nil {
+		return a.attachToSession(s)
+	}
+
+	// No exact match - attach to the one session the query clearly points at
+	list := func() []*session.Session {
+		return a.rank(a.sessionFinder.ListExcluding(false, currentSession))
+	}
+	if best := a.matcher.Best(list(), query); best != nil {
+		return a.attachToSession(best)
+	}
+
+	// No match or no clear winner - need selection
+	return a.pick(list, query)
+}
+
+// runKill kills the running sessions picked by the query, after asking for
+// confirmation. Without an exact or clear best match the user picks one or
+// more sessions in the picker. --all-but-current picks every running session but
+// the current one, narrowed down by the query when one is given.
+func (a *App) runKill(query, currentSession string, allButCurrent bool) error {
+	targets, err := a.killTargets(query, currentSession, allButCurrent)
+	if err != nil || len(targets) == 0 {
 		return err
 	}
-	if selected != nil {
-		if err := a.attachToSession(selected); err != nil {
-			return err
+	return a.confirmKill(targets)
+}
+
+// confirmKill asks before killing the sessions and reports the ones that
+// could not be killed.
+func (a *App) confirmKill(targets []*session.Session) error {
+	names := make([]string, len(targets))
+	for i, s := range targets {
+		names[i] = s.Name
+	}
+	ok, err := a.confirm(fmt.Sprintf("Kill %s?", strings.Join(names, ", ")))
+	if err != nil || !ok {
+		return nil
+	}
+
+	var failed []string
+	for _, s := range targets {
+		if err := a.killSession(s); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			failed = append(failed, s.Name)
 		}
 	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not kill %s", strings.Join(failed, ", "))
+	}
 	return nil
 }
 
-func (a *App) runWithQuery(query, currentSession string) error {
-	if a.handleBuiltinCommand(query) {
-		return nil
+func (a *App) killTargets(query, currentSession string, allButCurrent bool) ([]*session.Session, error) {
+	running := a.rank(a.sessionFinder.List(true))
+
+	if allButCurrent {
+		// Only ever kill sessions starting with the query, however loosely
+		// the match setting lets other commands match.
+		var targets []*session.Session
+		for _, s := range a.matcher.filterPrefix(running, query) {
+			if s.Name != currentSession {
+				targets = append(targets, s)
+			}
+		}
+		if len(targets) == 0 {
+			printNoSessions(query)
+		}
+		return targets, nil
 	}
 
-	// Try exact match first
-	session, err := a.sessionFinder.Find(query)
-	if err != nil {
-		return fmt.Errorf("error finding session: %w", err)
+	if query != "" {
+		s, err := a.sessionFinder.Find(query)
+		if err != nil {
+			return nil, fmt.Errorf("error finding session: %w", err)
+		}
+		if s != nil && s.Exists {
+			return []*session.Session{s}, nil
+		}
+		if best := a.matcher.Best(running, query); best != nil {
+			return []*session.Session{best}, nil
+		}
+	}
+
+	return a.selectSessions(running, query)
+}
+
+// runRename renames a running session, picking it like runKill does and
+// asking for the new name when it is not given. When the session is defined
+// in the config file tm offers to rename it there too, so it stays linked to
+// its aliases and hooks.
+func (a *App) runRename(args []string) error {
+	target, err := a.renameTarget(firstArg(args))
+	if err != nil || target == nil {
+		return err
+	}
+
+	var name string
+	if len(args) == 2 {
+		name = args[1]
+	} else {
+		name, err = a.prompt(fmt.Sprintf("New name for %s:", target.Name))
+		if err != nil {
+			return nil
+		}
 	}
-	if session != nil {
-		return a.attachToSession(session)
+	return a.renameSession(target, name)
+}
+
+// renameSession renames the running session, moves its history along and
+// offers to rename its entry in the config file, or the included file that
+// defines it, too.
+func (a *App) renameSession(target *session.Session, name string) error {
+	if err := a.checkNewName(target, name); err != nil {
+		return err
 	}
 
-	// No exact match - filter by prefix
-	allSessions := a.sessionFinder.ListExcluding(false, currentSession)
-	matches := filterSessions(allSessions, query)
+	a.debugMsg(fmt.Sprintf("Renaming session %s to %s", target.Name, name))
+	if err := a.tmuxClient.RenameSession(target, name); err != nil {
+		return fmt.Errorf("error renaming session: %w", err)
+	}
 
-	if len(matches) == 1 {
-		// Single prefix match - attach directly
-		return a.attachToSession(matches[0])
+	if err := a.historyStore.Rename(target.Name, name); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not update history: %v\n", err)
 	}
 
-	// 0 or >1 matches - need selection
-	selected, err := a.selectSession(allSessions, query)
+	files, err := a.configFile.SessionFiles(target.Name)
 	if err != nil {
-		return err
+		fmt.Fprintf(os.Stderr, "Warning: could not check the config file: %v\n", err)
+		return nil
 	}
-	if selected != nil {
-		return a.attachToSession(selected)
+	if len(files) == 0 {
+		return nil
 	}
 
+	defined := strings.Join(files, " and ")
+	update, err := a.confirm(fmt.Sprintf("%s is defined in %s. Rename it there too?", target.Name, defined))
+	if err == nil && update {
+		if err := a.configFile.RenameSession(target.Name, name); err != nil {
+			return fmt.Errorf("error updating config file: %w", err)
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s no longer matches its entry in %s\n", name, defined)
+	return nil
+}
+
+func (a *App) renameTarget(query string) (*session.Session, error) {
+	running := a.rank(a.sessionFinder.List(true))
+
+	if query != "" {
+		s, err := a.sessionFinder.Find(query)
+		if err != nil {
+			return nil, fmt.Errorf("error finding session: %w", err)
+		}
+		if s != nil && s.Exists {
+			return s, nil
+		}
+		if best := a.matcher.Best(running, query); best != nil {
+			return best, nil
+		}
+	}
+
+	return a.selectSession(running, query)
+}
+
+// checkNewName refuses names tmux would change and names already used by
+// another session, either as its name or as an alias.
+func (a *App) checkNewName(target *session.Session, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("a new name is required")
+	}
+	if strings.ContainsAny(name, ".:") {
+		return fmt.Errorf("session names cannot contain '.' or ':'")
+	}
+	if name == target.Name {
+		return fmt.Errorf("session is already named %q", name)
+	}
+	// Its own aliases are taken too, or its entry in the config file would
+	// end up with an alias that shadows its name.
+	if slices.Contains(target.Aliases, name) {
+		return fmt.Errorf("%q is already an alias of %s", name, target.Name)
+	}
+
+	for _, s := range a.sessionFinder.List(false) {
+		if s.Name != target.Name && s.Name == name {
+			return fmt.Errorf("a session named %q already exists", name)
+		}
+		if slices.Contains(s.Aliases, name) {
+			return fmt.Errorf("%q is already an alias of %s", name, s.Name)
+		}
+	}
 	return nil
 }
 
 func (a *App) selectSession(sessions []*session.Session, query string) (*session.Session, error) {
 	if len(sessions) == 0 {
-		if query == "" {
-			fmt.Println("No sessions available")
-		} else {
-			fmt.Printf("No sessions matching %q\n", query)
+		printNoSessions(query)
+		return nil, nil
+	}
+
+	if a.picker.IsAvailable() {
+		items := make([]string, len(sessions))
+		for i, s := range sessions {
+			items[i] = formatSessionLine(s)
+		}
+
+		opts, cleanup := a.previewOptions(sessions)
+		defer cleanup()
+
+		sel, ok, err := a.picker.Select(items, query, opts)
+		if err != nil {
+			return nil, err
 		}
+		if !ok {
+			return nil, nil // user cancelled
+		}
+
+		return sessions[sel.Index], nil
+	}
+
+	printAvailableSessions(sessions, query)
+	return nil, nil
+}
+
+// selectSessions is selectSession for picking several sessions at once.
+func (a *App) selectSessions(sessions []*session.Session, query string) ([]*session.Session, error) {
+	if len(sessions) == 0 {
+		printNoSessions(query)
 		return nil, nil
 	}
 
-	if a.fzfClient.IsAvailable() {
+	if a.picker.IsAvailable() {
 		items := make([]string, len(sessions))
 		for i, s := range sessions {
 			items[i] = formatSessionLine(s)
 		}
 
-		idx, ok, err := a.fzfClient.Select(items, query)
+		opts, cleanup := a.previewOptions(sessions)
+		defer cleanup()
+
+		idxs, ok, err := a.picker.SelectMulti(items, query, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -191,26 +526,53 @@ func (a *App) selectSession(sessions []*session.Session, query string) (*session
 			return nil, nil // user cancelled
 		}
 
-		return sessions[idx], nil
+		selected := make([]*session.Session, len(idxs))
+		for i, idx := range idxs {
+			selected[i] = sessions[idx]
+		}
+		return selected, nil
+	}
+
+	printAvailableSessions(sessions, query)
+	return nil, nil
+}
+
+func printNoSessions(query string) {
+	if query == "" {
+		fmt.Println("No sessions available")
+	} else {
+		fmt.Printf("No sessions matching %q\n", query)
 	}
+}
 
-	// No fzf - print list
+// printAvailableSessions lists the sessions when no picker is available.
+func printAvailableSessions(sessions []*session.Session, query string) {
 	fmt.Println("Available sessions:")
 	for _, s := range sessions {
 		fmt.Println(formatSessionLine(s))
 	}
 	fmt.Printf("\nProvide a more specific name (query: %q)\n", query)
-	return nil, nil
 }
 
 func (a *App) attachToSession(s *session.Session) error {
-	if a.tmuxClient.InsideTmux() {
-		if !s.Exists {
-			a.debugMsg(fmt.Sprintf("Creating new session: %s", s.Name))
-			if err := a.tmuxClient.NewSession(s); err != nil {
-				return fmt.Errorf("error creating session: %w", err)
-			}
+	if !s.Exists {
+		if err := a.checkProjectTrust(s); err != nil {
+			return err
+		}
+		if err := a.createSession(s); err != nil {
+			return err
 		}
+	}
+
+	if err := a.runHooks("on_attach", s.Hooks.OnAttach, s); err != nil {
+		return err
+	}
+
+	if err := a.historyStore.Record(s.Name, s.Dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record history: %v\n", err)
+	}
+
+	if a.tmuxClient.InsideTmux() {
 		a.debugMsg(fmt.Sprintf("Switching to session: %s", s.Name))
 		if err := a.tmuxClient.SwitchSession(s); err != nil {
 			return fmt.Errorf("error switching session: %w", err)
@@ -218,12 +580,6 @@ func (a *App) attachToSession(s *session.Session) error {
 		return nil
 	}
 
-	if !s.Exists {
-		a.debugMsg(fmt.Sprintf("Creating new session: %s", s.Name))
-		if err := a.tmuxClient.NewSession(s); err != nil {
-			return fmt.Errorf("error creating session: %w", err)
-		}
-	}
 	a.debugMsg(fmt.Sprintf("Attaching to session: %s", s.Name))
 	if err := a.tmuxClient.AttachSession(s); err != nil {
 		return fmt.Errorf("error attaching to session: %w", err)
@@ -231,28 +587,122 @@ func (a *App) attachToSession(s *session.Session) error {
 	return nil
 }
 
+func (a *App) createSession(s *session.Session) error {
+	if err := a.runHooks("on_create", s.Hooks.OnCreate, s); err != nil {
+		return err
+	}
+
+	a.debugMsg(fmt.Sprintf("Creating new session: %s", s.Name))
+	if err := a.tmuxClient.NewSession(s); err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+
+	// tm has already handed over to tmux by the time a client detaches, so
+	// on_detach hooks are registered with tmux instead of run here.
+	for _, h := range s.Hooks.OnDetach {
+		if err := a.tmuxClient.AddHook(s, "client-detached", a.hookRunner.Script("on_detach", h, s)); err != nil {
+			return fmt.Errorf("error registering on_detach hook: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkProjectTrust asks before running commands or setting env from a
+// project file whose current content has not been answered for yet, and
+// remembers the answer. When the file is trusted its env is added to the
+// session env, overriding the smart directory's. When it is not, its layout
+// is still used, without commands or env.
+func (a *App) checkProjectTrust(s *session.Session) error {
+	p := s.ProjectFile
+	if p == nil || (!hasCommands(s) && len(s.ProjectEnv) == 0) {
+		return nil
+	}
+
+	trusted, ok := a.trustStore.Lookup(p.Path, p.Hash)
+	if !ok {
+		answer, err := a.confirm(fmt.Sprintf("%s is new or has changed and wants to run commands or set environment variables. Trust it?", p.Path))
+		// Without an answer, e.g. when stdin is not a terminal, skip the
+		// commands this time and ask again on the next run.
+		if err == nil {
+			trusted = answer
+			if err := a.trustStore.Save(p.Path, p.Hash, trusted); err != nil {
+				return fmt.Errorf("error saving project trust: %w", err)
+			}
+		}
+	}
+
+	if !trusted {
+		a.debugMsg(fmt.Sprintf("Skipping commands and env from untrusted project file: %s", p.Path))
+		clearCommands(s)
+		s.ProjectEnv = nil
+		return nil
+	}
+
+	if len(s.ProjectEnv) > 0 {
+		env := maps.Clone(s.Env)
+		if env == nil {
+			env = make(map[string]string, len(s.ProjectEnv))
+		}
+		maps.Copy(env, s.ProjectEnv)
+		s.Env = env
+	}
+	return nil
+}
+
+func (a *App) killSession(s *session.Session) error {
+	if err := a.runHooks("on_kill", s.Hooks.OnKill, s); err != nil {
+		return err
+	}
+
+	a.debugMsg(fmt.Sprintf("Killing session: %s", s.Name))
+	if err := a.tmuxClient.KillSession(s); err != nil {
+		return fmt.Errorf("error killing session: %w", err)
+	}
+	return nil
+}
+
+// runHooks runs hooks in order. A failing hook is reported and the remaining
+// hooks still run, unless the hook asks to abort.
+func (a *App) runHooks(event string, hooks []session.Hook, s *session.Session) error {
+	for _, h := range hooks {
+		a.debugMsg(fmt.Sprintf("Running %s hook: %s", event, h.Command))
+		if err := a.hookRunner.Run(event, h, s); err != nil {
+			if h.Abort {
+				return fmt.Errorf("%s hook %q failed: %w", event, h.Command, err)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %s hook %q failed: %v\n", event, h.Command, err)
+		}
+	}
+	return nil
+}
+
 func (a *App) debugMsg(msg string) {
 	if a.debug {
 		fmt.Println(msg)
 	}
 }
 
-func (a *App) handleBuiltinCommand(input string) bool {
-	switch input {
-	case "ls", "list":
-		a.printSessionList(true)
-		return true
-	case "ls-all", "list-all":
-		a.printSessionList(false)
-		return true
+func (a *App) printSessionList(onlyExisting bool, format, source string) error {
+	sources, err := parseSources(source)
+	if err != nil {
+		return err
 	}
-	return false
+	sessions := filterSources(a.sessionFinder.List(onlyExisting), sources)
+	return writeSessions(os.Stdout, a.rank(sessions), format)
 }
 
-func (a *App) printSessionList(onlyExisting bool) {
-	for _, s := range a.sessionFinder.List(onlyExisting) {
-		fmt.Println(formatSessionLine(s))
+// rank orders sessions by their frecency score, highest first. Sessions
+// with equal scores, including those never attached through tm, keep the
+// order the finder returned them in.
+func (a *App) rank(sessions []*session.Session) []*session.Session {
+	scores := a.historyStore.Scores()
+	if len(scores) == 0 {
+		return sessions
 	}
+	slices.SortStableFunc(sessions, func(x, y *session.Session) int {
+		return cmp.Compare(scores[y.Name], scores[x.Name])
+	})
+	return sessions
 }
 
 func formatSessionLine(s *session.Session) string {
@@ -264,31 +714,74 @@ func formatSessionLine(s *session.Session) string {
 	if s.Exists {
 		line += " *"
 	}
+	if s.Socket != "" {
+		line += " @" + s.Socket
+	}
+	if s.Source != "" {
+		line += fmt.Sprintf(" <%s>", s.Source)
+	}
 	return line
 }
 
-func printStatusLine(name, status string) {
-	dots := strings.Repeat(".", 12-len(name))
-	fmt.Printf("%s%s %s\n", name, dots, status)
+// confirm asks a yes/no question on the terminal. Anything but an explicit
+// yes counts as no, and an error is returned when no answer could be read.
+func (a *App) confirm(question string) (bool, error) {
+	answer, err := a.prompt(question + " [y/N]")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
 }
 
-func filterSessions(sessions []*session.Session, query string) []*session.Session {
-	if query == "" {
-		return sessions
+// prompt asks a question on the terminal and returns the trimmed answer, or
+// an error when no answer could be read. Every question reads from the same
+// reader, so answers piped in together are not lost to its buffer.
+func (a *App) prompt(question string) (string, error) {
+	if a.stdin == nil {
+		a.stdin = bufio.NewReader(os.Stdin)
 	}
-	query = strings.ToLower(query)
-	var matches []*session.Session
-	for _, s := range sessions {
-		if strings.HasPrefix(strings.ToLower(s.Name), query) {
-			matches = append(matches, s)
-			continue
-		}
-		for _, alias := range s.Aliases {
-			if strings.HasPrefix(strings.ToLower(alias), query) {
-				matches = append(matches, s)
-				break
+	fmt.Printf("%s ", question)
+	answer, err := a.stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+func hasCommands(s *session.Session) bool {
+	for _, w := range s.Windows {
+		if w.Command != "" {
+			return true
+		}
+		for _, p := range w.Panes {
+			if p.Command != "" {
+				return true
 			}
 		}
 	}
-	return matches
+	return false
+}
+
+func clearCommands(s *session.Session) {
+	for i := range s.Windows {
+		s.Windows[i].Command = ""
+		for j := range s.Windows[i].Panes {
+			s.Windows[i].Panes[j].Command = ""
+		}
+	}
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func printStatusLine(name, status string) {
+	dots := strings.Repeat(".", 12-len(name))
+	fmt.Printf("%s%s %s\n", name, dots, status)
 }
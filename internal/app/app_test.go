@@ -2,12 +2,19 @@ package app
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/griggsjared/tm/internal/cli"
+	"github.com/griggsjared/tm/internal/fzf"
+	"github.com/griggsjared/tm/internal/history"
 	"github.com/griggsjared/tm/internal/session"
+	"github.com/griggsjared/tm/internal/tmux"
 )
 
 type mockTmuxClient struct {
@@ -19,10 +26,23 @@ type mockTmuxClient struct {
 	newSessionCalled    bool
 	attachSessionCalled bool
 	switchSessionCalled bool
+	killSessionCalled   bool
 	newSessionError     error
 	attachSessionError  error
 	switchSessionError  error
+	killSessionError    error
 	lastSession         *session.Session
+	addedHooks          []string
+	killed              []string
+	renamed             []string
+	renameSessionError  error
+	socket              tmux.Socket
+	windows             string
+	pane                string
+	windowsError        error
+	newWindows          []string
+	popups              []string
+	popupError          error
 }
 
 func (m *mockTmuxClient) IsAvailable() bool {
@@ -51,6 +71,33 @@ func (m *mockTmuxClient) NewSession(s *session.Session) error {
 	return m.newSessionError
 }
 
+func (m *mockTmuxClient) KillSession(s *session.Session) error {
+	m.killSessionCalled = true
+	m.lastSession = s
+	m.killed = append(m.killed, s.Name)
+	return m.killSessionError
+}
+
+func (m *mockTmuxClient) RenameSession(s *session.Session, name string) error {
+	m.renamed = append(m.renamed, s.Name+" -> "+name)
+	return m.renameSessionError
+}
+
+func (m *mockTmuxClient) NewWindow(s *session.Session, name, dir string) error {
+	m.newWindows = append(m.newWindows, s.Name+": "+name+" "+dir)
+	return nil
+}
+
+func (m *mockTmuxClient) DisplayPopup(width, height, border, dir, command string) error {
+	m.popups = append(m.popups, strings.Join([]string{width, height, border, dir, command}, "|"))
+	return m.popupError
+}
+
+func (m *mockTmuxClient) AddHook(s *session.Session, event, command string) error {
+	m.addedHooks = append(m.addedHooks, event+": "+command)
+	return nil
+}
+
 func (m *mockTmuxClient) AttachSession(s *session.Session) error {
 	m.attachSessionCalled = true
 	m.lastSession = s
@@ -63,6 +110,18 @@ func (m *mockTmuxClient) SwitchSession(s *session.Session) error {
 	return m.switchSessionError
 }
 
+func (m *mockTmuxClient) ListWindows(s *session.Session) (string, error) {
+	return m.windows, m.windowsError
+}
+
+func (m *mockTmuxClient) CapturePane(s *session.Session) (string, error) {
+	return m.pane, nil
+}
+
+func (m *mockTmuxClient) Socket() tmux.Socket {
+	return m.socket
+}
+
 type mockSessionFinder struct {
 	findCalled           bool
 	listCalled           bool
@@ -89,6 +148,110 @@ func (m *mockSessionFinder) ListExcluding(onlyExisting bool, exclude string) []*
 	return m.listResult
 }
 
+type mockHookRunner struct {
+	ran    []string
+	errors map[string]error
+}
+
+func (m *mockHookRunner) Run(event string, h session.Hook, s *session.Session) error {
+	m.ran = append(m.ran, event+": "+h.Command)
+	return m.errors[h.Command]
+}
+
+func (m *mockHookRunner) Script(event string, h session.Hook, s *session.Session) string {
+	return h.Command
+}
+
+type mockTrustStore struct {
+	known   bool
+	trusted bool
+	saved   []string
+	saveErr error
+}
+
+func (m *mockTrustStore) Lookup(path, hash string) (bool, bool) {
+	return m.trusted, m.known
+}
+
+func (m *mockTrustStore) Save(path, hash string, trusted bool) error {
+	m.saved = append(m.saved, fmt.Sprintf("%s@%s=%v", path, hash, trusted))
+	return m.saveErr
+}
+
+type mockHistoryStore struct {
+	recorded []string
+	renamed  []string
+	scores   map[string]float64
+	entries  []history.Entry
+	pruned   []history.Entry
+	err      error
+}
+
+func (m *mockHistoryStore) Record(name, dir string) error {
+	m.recorded = append(m.recorded, name)
+	return m.err
+}
+
+func (m *mockHistoryStore) Rename(oldName, newName string) error {
+	m.renamed = append(m.renamed, oldName+" -> "+newName)
+	return m.err
+}
+
+func (m *mockHistoryStore) Scores() map[string]float64 {
+	return m.scores
+}
+
+func (m *mockHistoryStore) Entries() ([]history.Entry, error) {
+	return m.entries, m.err
+}
+
+func (m *mockHistoryStore) Prune(remove func(history.Entry) bool) ([]history.Entry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var kept []history.Entry
+	for _, e := range m.entries {
+		if remove(e) {
+			m.pruned = append(m.pruned, e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.entries = kept
+	return m.pruned, nil
+}
+
+type mockConfigFile struct {
+	sessions  map[string][]string
+	renamed   []string
+	hasErr    error
+	renameErr error
+}
+
+func (m *mockConfigFile) Path() string {
+	return "/home/me/.config/tm/config.yaml"
+}
+
+func (m *mockConfigFile) SessionFiles(name string) ([]string, error) {
+	return m.sessions[name], m.hasErr
+}
+
+func (m *mockConfigFile) RenameSession(oldName, newName string) error {
+	m.renamed = append(m.renamed, oldName+" -> "+newName)
+	return m.renameErr
+}
+
+type mockPreviewer struct {
+	dirs []string
+	err  error
+}
+
+func (m *mockPreviewer) Project(w io.Writer, dir string) error {
+	m.dirs = append(m.dirs, dir)
+	fmt.Fprintf(w, "project %s\n", dir)
+	return m.err
+}
+
 type mockFzfClient struct {
 	available     bool
 	path          string
@@ -99,6 +262,13 @@ type mockFzfClient struct {
 	selectCalled  bool
 	providedItems []string
 	providedQuery string
+	providedOpts  fzf.Options
+	multiResult   []int
+	multiCalled   bool
+	// actions, when set, are returned by successive Select calls, after
+	// which the picker is cancelled.
+	actions     []fzf.Selection
+	selectCalls int
 }
 
 func (m *mockFzfClient) IsAvailable() bool {
@@ -113,11 +283,38 @@ func (m *mockFzfClient) Version() string {
 	return m.version
 }
 
-func (m *mockFzfClient) Select(items []string, query string) (int, bool, error) {
+func (m *mockFzfClient) Select(items []string, query string, opts fzf.Options) (fzf.Selection, bool, error) {
 	m.selectCalled = true
+	m.selectCalls++
 	m.providedItems = items
 	m.providedQuery = query
-	return m.selectResult, m.selectOk, m.selectError
+	m.providedOpts = opts
+	if m.actions != nil {
+		if len(m.actions) == 0 {
+			return fzf.Selection{}, false, nil
+		}
+		sel := m.actions[0]
+		m.actions = m.actions[1:]
+		return sel, true, nil
+	}
+	return fzf.Selection{Index: m.selectResult, Query: query}, m.selectOk, m.selectError
+}
+
+func (m *mockFzfClient) SelectMulti(items []string, query string, opts fzf.Options) ([]int, bool, error) {
+	m.multiCalled = true
+	m.providedItems = items
+	m.providedQuery = query
+	m.providedOpts = opts
+	return m.multiResult, m.selectOk, m.selectError
+}
+
+func parseArgs(t *testing.T, args ...string) cli.Command {
+	t.Helper()
+	cmd, err := cli.Parse(args)
+	if err != nil {
+		t.Fatalf("cli.Parse(%v) error: %v", args, err)
+	}
+	return cmd
 }
 
 func TestFilterSessions(t *testing.T) {
@@ -216,7 +413,7 @@ func TestFilterSessions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := filterSessions(tt.sessions, tt.query)
+			got := Matcher{}.Filter(tt.sessions, tt.query)
 			if len(got) != tt.wantLen {
 				t.Errorf("expected %d sessions, got %d", tt.wantLen, len(got))
 			}
@@ -319,7 +516,7 @@ func TestAppAttachToSession(t *testing.T) {
 				attachSessionError: tt.attachSessionErr,
 				switchSessionError: tt.switchSessionErr,
 			}
-			app := New(tmuxMock, &mockFzfClient{}, &mockSessionFinder{}, false, "test")
+			app := New(tmuxMock, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
 			err := app.attachToSession(tt.session)
 
 			if (err != nil) != tt.wantErr {
@@ -405,7 +602,7 @@ func TestAppSelectSession(t *testing.T) {
 			}
 			sessionMock := &mockSessionFinder{}
 
-			app := New(tmuxMock, fzfMock, sessionMock, false, "test")
+			app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
 			got, err := app.selectSession(tt.sessions, tt.query)
 
 			if (err != nil) != tt.wantErr {
@@ -430,16 +627,669 @@ func TestAppSelectSession(t *testing.T) {
 var _ TmuxClient = &mockTmuxClient{}
 var _ SessionFinder = &mockSessionFinder{}
 var _ FzfClient = &mockFzfClient{}
+var _ HookRunner = &mockHookRunner{}
+var _ TrustStore = &mockTrustStore{}
+
+func TestAppCheckProjectTrust(t *testing.T) {
+	projectSession := func() *session.Session {
+		return &session.Session{
+			Name:        "api",
+			ProjectFile: &session.ProjectFile{Path: "/src/api/.tm.yaml", Hash: "abc"},
+			Windows: []session.Window{
+				{Command: "make", Panes: []session.Pane{{Command: "nvim ."}, {}}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		session      *session.Session
+		store        *mockTrustStore
+		stdin        string
+		closeStdin   bool
+		saveErr      error
+		wantCommands bool
+		wantSaved    []string
+		wantErr      bool
+	}{
+		{
+			name:         "session without project file is untouched",
+			session:      &session.Session{Name: "api", Windows: []session.Window{{Command: "make"}}},
+			store:        &mockTrustStore{},
+			wantCommands: true,
+		},
+		{
+			name:    "project file without commands does not ask",
+			session: &session.Session{Name: "api", ProjectFile: &session.ProjectFile{Path: "/src/api/.tm.yaml"}, Windows: []session.Window{{Name: "main"}}},
+			store:   &mockTrustStore{},
+		},
+		{
+			name:         "remembered trust keeps commands",
+			session:      projectSession(),
+			store:        &mockTrustStore{known: true, trusted: true},
+			wantCommands: true,
+		},
+		{
+			name:    "remembered denial clears commands",
+			session: projectSession(),
+			store:   &mockTrustStore{known: true, trusted: false},
+		},
+		{
+			name:         "answering yes trusts and remembers",
+			session:      projectSession(),
+			store:        &mockTrustStore{},
+			stdin:        "y\n",
+			wantCommands: true,
+			wantSaved:    []string{"/src/api/.tm.yaml@abc=true"},
+		},
+		{
+			name:      "answering no denies and remembers",
+			session:   projectSession(),
+			store:     &mockTrustStore{},
+			stdin:     "\n",
+			wantSaved: []string{"/src/api/.tm.yaml@abc=false"},
+		},
+		{
+			name:       "no answer denies without remembering",
+			session:    projectSession(),
+			store:      &mockTrustStore{},
+			closeStdin: true,
+		},
+		{
+			name:         "save error is returned",
+			session:      projectSession(),
+			store:        &mockTrustStore{saveErr: errors.New("read-only")},
+			stdin:        "yes\n",
+			wantCommands: true,
+			wantSaved:    []string{"/src/api/.tm.yaml@abc=true"},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldStdin, oldStdout := os.Stdin, os.Stdout
+			r, w, _ := os.Pipe()
+			w.WriteString(tt.stdin)
+			if tt.stdin != "" || tt.closeStdin {
+				w.Close()
+			}
+			os.Stdin = r
+			_, out, _ := os.Pipe()
+			os.Stdout = out
+			defer func() { os.Stdin, os.Stdout = oldStdin, oldStdout }()
+
+			app := New(&mockTmuxClient{}, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, tt.store, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+			err := app.checkProjectTrust(tt.session)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkProjectTrust() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := hasCommands(tt.session); got != tt.wantCommands {
+				t.Errorf("hasCommands() = %v, want %v", got, tt.wantCommands)
+			}
+			if !slices.Equal(tt.store.saved, tt.wantSaved) {
+				t.Errorf("saved = %v, want %v", tt.store.saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestAppCheckProjectTrust_Env(t *testing.T) {
+	envSession := func() *session.Session {
+		return &session.Session{
+			Name:        "api",
+			Env:         map[string]string{"AWS_PROFILE": "default", "GOFLAGS": "-mod=mod"},
+			ProjectFile: &session.ProjectFile{Path: "/src/api/.tm.yaml", Hash: "abc"},
+			ProjectEnv:  map[string]string{"AWS_PROFILE": "api", "BASH_ENV": "./evil.sh"},
+			Windows:     []session.Window{{Name: "main"}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		store     *mockTrustStore
+		stdin     string
+		wantEnv   map[string]string
+		wantSaved []string
+	}{
+		{
+			name:      "env alone asks and is dropped when denied",
+			store:     &mockTrustStore{},
+			stdin:     "n\n",
+			wantEnv:   map[string]string{"AWS_PROFILE": "default", "GOFLAGS": "-mod=mod"},
+			wantSaved: []string{"/src/api/.tm.yaml@abc=false"},
+		},
+		{
+			name:    "remembered denial drops env",
+			store:   &mockTrustStore{known: true, trusted: false},
+			wantEnv: map[string]string{"AWS_PROFILE": "default", "GOFLAGS": "-mod=mod"},
+		},
+		{
+			name:    "trusted env overrides the smart directory env",
+			store:   &mockTrustStore{known: true, trusted: true},
+			wantEnv: map[string]string{"AWS_PROFILE": "api", "BASH_ENV": "./evil.sh", "GOFLAGS": "-mod=mod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldStdin, oldStdout := os.Stdin, os.Stdout
+			r, w, _ := os.Pipe()
+			w.WriteString(tt.stdin)
+			w.Close()
+			os.Stdin = r
+			_, out, _ := os.Pipe()
+			os.Stdout = out
+			defer func() { os.Stdin, os.Stdout = oldStdin, oldStdout }()
+
+			s := envSession()
+			app := New(&mockTmuxClient{}, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, tt.store, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+			if err := app.checkProjectTrust(s); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !maps.Equal(s.Env, tt.wantEnv) {
+				t.Errorf("Env = %v, want %v", s.Env, tt.wantEnv)
+			}
+			if !slices.Equal(tt.store.saved, tt.wantSaved) {
+				t.Errorf("saved = %v, want %v", tt.store.saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestAppAttachToSession_Hooks(t *testing.T) {
+	hooks := session.Hooks{
+		OnCreate: []session.Hook{{Command: "direnv allow"}},
+		OnAttach: []session.Hook{{Command: "vpn up"}},
+		OnDetach: []session.Hook{{Command: "vpn down"}},
+	}
+
+	tests := []struct {
+		name             string
+		session          *session.Session
+		hookErrors       map[string]error
+		wantRan          []string
+		wantAddedHooks   []string
+		wantNewCalled    bool
+		wantSwitchCalled bool
+		wantErr          bool
+	}{
+		{
+			name:             "new session runs create and attach hooks",
+			session:          &session.Session{Name: "test", Hooks: hooks},
+			wantRan:          []string{"on_create: direnv allow", "on_attach: vpn up"},
+			wantAddedHooks:   []string{"client-detached: vpn down"},
+			wantNewCalled:    true,
+			wantSwitchCalled: true,
+		},
+		{
+			name:             "existing session only runs attach hooks",
+			session:          &session.Session{Name: "test", Exists: true, Hooks: hooks},
+			wantRan:          []string{"on_attach: vpn up"},
+			wantSwitchCalled: true,
+		},
+		{
+			name:             "failing hook without abort is reported and attach continues",
+			session:          &session.Session{Name: "test", Exists: true, Hooks: hooks},
+			hookErrors:       map[string]error{"vpn up": errors.New("exit status 1")},
+			wantRan:          []string{"on_attach: vpn up"},
+			wantSwitchCalled: true,
+		},
+		{
+			name: "failing create hook with abort stops creation",
+			session: &session.Session{Name: "test", Hooks: session.Hooks{
+				OnCreate: []session.Hook{{Command: "nvm use", Abort: true}, {Command: "never"}},
+			}},
+			hookErrors: map[string]error{"nvm use": errors.New("exit status 3")},
+			wantRan:    []string{"on_create: nvm use"},
+			wantErr:    true,
+		},
+		{
+			name: "failing attach hook with abort stops the attach",
+			session: &session.Session{Name: "test", Exists: true, Hooks: session.Hooks{
+				OnAttach: []session.Hook{{Command: "vpn up", Abort: true}},
+			}},
+			hookErrors: map[string]error{"vpn up": errors.New("exit status 1")},
+			wantRan:    []string{"on_attach: vpn up"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmuxMock := &mockTmuxClient{available: true, insideTmux: true}
+			hookMock := &mockHookRunner{errors: tt.hookErrors}
+
+			oldStderr := os.Stderr
+			_, w, _ := os.Pipe()
+			os.Stderr = w
+			defer func() { os.Stderr = oldStderr }()
+
+			app := New(tmuxMock, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, hookMock, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+			err := app.attachToSession(tt.session)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("attachToSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(hookMock.ran, tt.wantRan) {
+				t.Errorf("hooks ran = %v, want %v", hookMock.ran, tt.wantRan)
+			}
+			if !slices.Equal(tmuxMock.addedHooks, tt.wantAddedHooks) {
+				t.Errorf("tmux hooks added = %v, want %v", tmuxMock.addedHooks, tt.wantAddedHooks)
+			}
+			if tmuxMock.newSessionCalled != tt.wantNewCalled {
+				t.Errorf("NewSession called = %v, want %v", tmuxMock.newSessionCalled, tt.wantNewCalled)
+			}
+			if tmuxMock.switchSessionCalled != tt.wantSwitchCalled {
+				t.Errorf("SwitchSession called = %v, want %v", tmuxMock.switchSessionCalled, tt.wantSwitchCalled)
+			}
+		})
+	}
+}
+
+func TestAppKillSession(t *testing.T) {
+	tests := []struct {
+		name           string
+		hooks          []session.Hook
+		hookErrors     map[string]error
+		killErr        error
+		wantKillCalled bool
+		wantErr        bool
+	}{
+		{
+			name:           "runs kill hooks then kills",
+			hooks:          []session.Hook{{Command: "docker compose down"}},
+			wantKillCalled: true,
+		},
+		{
+			name:           "failing hook without abort still kills",
+			hooks:          []session.Hook{{Command: "docker compose down"}},
+			hookErrors:     map[string]error{"docker compose down": errors.New("exit status 1")},
+			wantKillCalled: true,
+		},
+		{
+			name:       "failing hook with abort keeps the session",
+			hooks:      []session.Hook{{Command: "docker compose down", Abort: true}},
+			hookErrors: map[string]error{"docker compose down": errors.New("exit status 1")},
+			wantErr:    true,
+		},
+		{
+			name:           "kill error is returned",
+			killErr:        errors.New("no such session"),
+			wantKillCalled: true,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmuxMock := &mockTmuxClient{available: true, killSessionError: tt.killErr}
+			hookMock := &mockHookRunner{errors: tt.hookErrors}
+
+			oldStderr := os.Stderr
+			_, w, _ := os.Pipe()
+			os.Stderr = w
+			defer func() { os.Stderr = oldStderr }()
+
+			app := New(tmuxMock, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, hookMock, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+			err := app.killSession(&session.Session{Name: "test", Exists: true, Hooks: session.Hooks{OnKill: tt.hooks}})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("killSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tmuxMock.killSessionCalled != tt.wantKillCalled {
+				t.Errorf("KillSession called = %v, want %v", tmuxMock.killSessionCalled, tt.wantKillCalled)
+			}
+			if len(tt.hooks) > 0 && len(hookMock.ran) != 1 {
+				t.Errorf("expected kill hook to run once, ran %v", hookMock.ran)
+			}
+		})
+	}
+}
+
+func TestApp_Run_Kill(t *testing.T) {
+	running := func() []*session.Session {
+		return []*session.Session{
+			{Name: "current", Exists: true},
+			{Name: "api", Exists: true, Hooks: session.Hooks{OnKill: []session.Hook{{Command: "docker compose down"}}}},
+			{Name: "app", Exists: true},
+			{Name: "web", Exists: true},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		args          []string
+		findResult    *session.Session
+		fzfAvailable  bool
+		multiResult   []int
+		fzfOk         bool
+		ranked        bool
+		stdin         string
+		killErr       error
+		wantExit      int
+		wantFzfCalled bool
+		wantKilled    []string
+		wantHooks     []string
+	}{
+		{
+			name:       "exact match",
+			args:       []string{"kill", "web"},
+			findResult: &session.Session{Name: "web", Exists: true},
+			stdin:      "y\n",
+			wantKilled: []string{"web"},
+		},
+		{
+			name:       "single prefix match runs on_kill hooks",
+			args:       []string{"kill", "api"},
+			stdin:      "y\n",
+			wantKilled: []string{"api"},
+			wantHooks:  []string{"on_kill: docker compose down"},
+		},
+		{
+			name:  "ambiguous prefix without fzf kills nothing",
+			args:  []string{"kill", "ap"},
+			stdin: "y\n",
+		},
+		{
+			name:       "pre-defined session that is not running is not killed",
+			args:       []string{"kill", "we"},
+			findResult: &session.Session{Name: "web-predefined"},
+			stdin:      "y\n",
+			wantKilled: []string{"web"},
+		},
+		{
+			name:          "fzf multi select",
+			args:          []string{"kill"},
+			fzfAvailable:  true,
+			multiResult:   []int{1, 3},
+			fzfOk:         true,
+			stdin:         "yes\n",
+			wantFzfCalled: true,
+			wantKilled:    []string{"api", "web"},
+			wantHooks:     []string{"on_kill: docker compose down"},
+		},
+		{
+			name:          "fzf cancelled",
+			args:          []string{"kill"},
+			fzfAvailable:  true,
+			wantFzfCalled: true,
+		},
+		{
+			name:       "all but current",
+			args:       []string{"kill", "--all-but-current"},
+			stdin:      "y\n",
+			wantKilled: []string{"api", "app", "web"},
+			wantHooks:  []string{"on_kill: docker compose down"},
+		},
+		{
+			name:       "all but current narrowed by query",
+			args:       []string{"kill", "--all-but-current", "ap"},
+			stdin:      "y\n",
+			wantKilled: []string{"api", "app"},
+			wantHooks:  []string{"on_kill: docker compose down"},
+		},
+		{
+			name:   "all but current only kills prefix matches when ranked",
+			args:   []string{"kill", "--all-but-current", "p"},
+			ranked: true,
+			stdin:  "y\n",
+		},
+		{
+			name:  "declined",
+			args:  []string{"kill", "--all-but-current"},
+			stdin: "n\n",
+		},
+		{
+			name: "no answer",
+			args: []string{"kill", "--all-but-current"},
+		},
+		{
+			name:       "kill error",
+			args:       []string{"kill", "web"},
+			findResult: &session.Session{Name: "web", Exists: true},
+			stdin:      "y\n",
+			killErr:    errors.New("no such session"),
+			wantExit:   1,
+			wantKilled: []string{"web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmuxMock := &mockTmuxClient{available: true, insideTmux: true, currentSession: "current", killSessionError: tt.killErr}
+			sessionMock := &mockSessionFinder{findResult: tt.findResult, listResult: running()}
+			fzfMock := &mockFzfClient{available: tt.fzfAvailable, multiResult: tt.multiResult, selectOk: tt.fzfOk}
+			hookMock := &mockHookRunner{}
+
+			oldStdin, oldStdout, oldStderr := os.Stdin, os.Stdout, os.Stderr
+			r, w, _ := os.Pipe()
+			w.WriteString(tt.stdin)
+			w.Close()
+			os.Stdin = r
+			_, out, _ := os.Pipe()
+			os.Stdout, os.Stderr = out, out
+			defer func() { os.Stdin, os.Stdout, os.Stderr = oldStdin, oldStdout, oldStderr }()
+
+			app := New(tmuxMock, fzfMock, fzfMock, sessionMock, hookMock, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{Matcher: Matcher{Ranked: tt.ranked}})
+			got := app.Run(parseArgs(t, tt.args...))
+
+			if got != tt.wantExit {
+				t.Errorf("Run(%v) = %d, want %d", tt.args, got, tt.wantExit)
+			}
+			if fzfMock.multiCalled != tt.wantFzfCalled {
+				t.Errorf("SelectMulti called = %v, want %v", fzfMock.multiCalled, tt.wantFzfCalled)
+			}
+			if !slices.Equal(tmuxMock.killed, tt.wantKilled) {
+				t.Errorf("killed %v, want %v", tmuxMock.killed, tt.wantKilled)
+			}
+			if !slices.Equal(hookMock.ran, tt.wantHooks) {
+				t.Errorf("hooks ran %v, want %v", hookMock.ran, tt.wantHooks)
+			}
+		})
+	}
+}
+
+func TestApp_Run_Rename(t *testing.T) {
+	sessions := func() []*session.Session {
+		return []*session.Session{
+			{Name: "api", Exists: true, Aliases: []string{"be"}},
+			{Name: "web", Exists: true},
+			{Name: "notes", Aliases: []string{"n"}},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		args             []string
+		findResult       *session.Session
+		fzfAvailable     bool
+		fzfOk            bool
+		fzfResult        int
+		stdin            string
+		configSessions   map[string][]string
+		renameErr        error
+		configRenameErr  error
+		wantExit         int
+		wantRenamed      []string
+		wantConfigRename []string
+		wantOutput       string
+	}{
+		{
+			name:        "old and new name",
+			args:        []string{"rename", "web", "frontend"},
+			findResult:  &session.Session{Name: "web", Exists: true},
+			wantRenamed: []string{"web -> frontend"},
+		},
+		{
+			name:        "new name is prompted",
+			args:        []string{"rename", "web"},
+			findResult:  &session.Session{Name: "web", Exists: true},
+			stdin:       "frontend\n",
+			wantRenamed: []string{"web -> frontend"},
+		},
+		{
+			name:         "old name picked with fzf",
+			args:         []string{"rename"},
+			fzfAvailable: true,
+			fzfOk:        true,
+			fzfResult:    1,
+			stdin:        "frontend\n",
+			wantRenamed:  []string{"web -> frontend"},
+		},
+		{
+			name:             "pre-defined session updates the config",
+			args:             []string{"rename", "api", "backend"},
+			findResult:       &session.Session{Name: "api", Exists: true, Aliases: []string{"be"}},
+			configSessions:   map[string][]string{"api": {"/home/me/.config/tm/config.yaml"}},
+			stdin:            "y\n",
+			wantRenamed:      []string{"api -> backend"},
+			wantConfigRename: []string{"api -> backend"},
+		},
+		{
+			name:             "new name and config answer piped together",
+			args:             []string{"rename", "api"},
+			findResult:       &session.Session{Name: "api", Exists: true},
+			configSessions:   map[string][]string{"api": {"/home/me/.config/tm/config.yaml"}},
+			stdin:            "backend\ny\n",
+			wantRenamed:      []string{"api -> backend"},
+			wantConfigRename: []string{"api -> backend"},
+		},
+		{
+			name:           "declining the config update warns",
+			args:           []string{"rename", "api", "backend"},
+			findResult:     &session.Session{Name: "api", Exists: true, Aliases: []string{"be"}},
+			configSessions: map[string][]string{"api": {"/home/me/.config/tm/config.yaml"}},
+			stdin:          "n\n",
+			wantRenamed:    []string{"api -> backend"},
+			wantOutput:     "no longer matches",
+		},
+		{
+			name:             "config update error",
+			args:             []string{"rename", "api", "backend"},
+			findResult:       &session.Session{Name: "api", Exists: true},
+			configSessions:   map[string][]string{"api": {"/home/me/.config/tm/config.yaml"}},
+			stdin:            "y\n",
+			configRenameErr:  errors.New("read-only"),
+			wantExit:         1,
+			wantRenamed:      []string{"api -> backend"},
+			wantConfigRename: []string{"api -> backend"},
+		},
+		{
+			name:             "session defined in an include",
+			args:             []string{"rename", "api", "backend"},
+			findResult:       &session.Session{Name: "api", Exists: true},
+			configSessions:   map[string][]string{"api": {"/home/me/.config/tm/work.yaml"}},
+			stdin:            "y\n",
+			wantRenamed:      []string{"api -> backend"},
+			wantConfigRename: []string{"api -> backend"},
+			wantOutput:       "api is defined in /home/me/.config/tm/work.yaml",
+		},
+		{
+			name:       "session name collision",
+			args:       []string{"rename", "web", "notes"},
+			findResult: &session.Session{Name: "web", Exists: true},
+			wantExit:   1,
+			wantOutput: "already exists",
+		},
+		{
+			name:       "alias collision",
+			args:       []string{"rename", "web", "be"},
+			findResult: &session.Session{Name: "web", Exists: true},
+			wantExit:   1,
+			wantOutput: "already an alias of api",
+		},
+		{
+			name:       "own alias collision",
+			args:       []string{"rename", "api", "be"},
+			findResult: &session.Session{Name: "api", Exists: true, Aliases: []string{"be"}},
+			wantExit:   1,
+			wantOutput: "already an alias of api",
+		},
+		{
+			name:       "name tmux would change",
+			args:       []string{"rename", "web", "my.site"},
+			findResult: &session.Session{Name: "web", Exists: true},
+			wantExit:   1,
+			wantOutput: "cannot contain",
+		},
+		{
+			name:       "empty name",
+			args:       []string{"rename", "web"},
+			findResult: &session.Session{Name: "web", Exists: true},
+			stdin:      "\n",
+			wantExit:   1,
+			wantOutput: "new name is required",
+		},
+		{
+			name:        "tmux error",
+			args:        []string{"rename", "web", "frontend"},
+			findResult:  &session.Session{Name: "web", Exists: true},
+			renameErr:   errors.New("duplicate session"),
+			wantExit:    1,
+			wantRenamed: []string{"web -> frontend"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmuxMock := &mockTmuxClient{available: true, renameSessionError: tt.renameErr}
+			sessionMock := &mockSessionFinder{findResult: tt.findResult, listResult: sessions()}
+			fzfMock := &mockFzfClient{available: tt.fzfAvailable, selectOk: tt.fzfOk, selectResult: tt.fzfResult}
+			configMock := &mockConfigFile{sessions: tt.configSessions, renameErr: tt.configRenameErr}
+
+			oldStdin, oldStdout, oldStderr := os.Stdin, os.Stdout, os.Stderr
+			in, w, _ := os.Pipe()
+			w.WriteString(tt.stdin)
+			w.Close()
+			os.Stdin = in
+			r, out, _ := os.Pipe()
+			os.Stdout, os.Stderr = out, out
+
+			historyMock := &mockHistoryStore{}
+
+			app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, historyMock, configMock, &mockPreviewer{}, Options{})
+			got := app.Run(parseArgs(t, tt.args...))
+
+			out.Close()
+			os.Stdin, os.Stdout, os.Stderr = oldStdin, oldStdout, oldStderr
+			output, _ := io.ReadAll(r)
+
+			if got != tt.wantExit {
+				t.Errorf("Run(%v) = %d, want %d", tt.args, got, tt.wantExit)
+			}
+			if !slices.Equal(tmuxMock.renamed, tt.wantRenamed) {
+				t.Errorf("tmux renamed %v, want %v", tmuxMock.renamed, tt.wantRenamed)
+			}
+			// The history follows the session whenever tmux renamed it.
+			wantHistory := tt.wantRenamed
+			if tt.renameErr != nil {
+				wantHistory = nil
+			}
+			if !slices.Equal(historyMock.renamed, wantHistory) {
+				t.Errorf("history renamed %v, want %v", historyMock.renamed, wantHistory)
+			}
+			if !slices.Equal(configMock.renamed, tt.wantConfigRename) {
+				t.Errorf("config renamed %v, want %v", configMock.renamed, tt.wantConfigRename)
+			}
+			if tt.wantOutput != "" && !strings.Contains(string(output), tt.wantOutput) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.wantOutput, output)
+			}
+		})
+	}
+}
 
 func TestApp_Run_TmuxUnavailable(t *testing.T) {
 	tmuxMock := &mockTmuxClient{available: false}
 	sessionMock := &mockSessionFinder{}
 	fzfMock := &mockFzfClient{available: false}
 
-	app := New(tmuxMock, fzfMock, sessionMock, false, "test")
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
 
 	// Run should return early without calling any session methods
-	app.Run("")
+	app.Run(parseArgs(t))
 
 	// Verify no session operations were attempted
 	if sessionMock.findCalled {
@@ -458,8 +1308,8 @@ func TestApp_Run_InsideTmux_NoArgs(t *testing.T) {
 	sessionMock := &mockSessionFinder{listResult: []*session.Session{{Name: "other"}}}
 	fzfMock := &mockFzfClient{available: false}
 
-	app := New(tmuxMock, fzfMock, sessionMock, false, "test")
-	app.Run("")
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	app.Run(parseArgs(t))
 
 	if !sessionMock.listExcludingCalled {
 		t.Error("ListExcluding should be called when inside tmux with no args")
@@ -474,8 +1324,8 @@ func TestApp_Run_OutsideTmux_NoArgs(t *testing.T) {
 	sessionMock := &mockSessionFinder{listResult: []*session.Session{{Name: "session1"}}}
 	fzfMock := &mockFzfClient{available: false}
 
-	app := New(tmuxMock, fzfMock, sessionMock, false, "test")
-	app.Run("")
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	app.Run(parseArgs(t))
 
 	if !sessionMock.listExcludingCalled {
 		t.Error("ListExcluding should be called when outside tmux with no args")
@@ -493,8 +1343,8 @@ func TestApp_Run_InsideTmux_ExactMatch(t *testing.T) {
 	}
 	fzfMock := &mockFzfClient{available: false}
 
-	app := New(tmuxMock, fzfMock, sessionMock, false, "test")
-	app.Run("exact")
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	app.Run(parseArgs(t, "exact"))
 
 	if !sessionMock.findCalled {
 		t.Error("Find should be called for exact match")
@@ -512,8 +1362,8 @@ func TestApp_Run_BuiltinsUseFullList(t *testing.T) {
 	sessionMock := &mockSessionFinder{listResult: []*session.Session{{Name: "my-session", Exists: true}}}
 	fzfMock := &mockFzfClient{available: false}
 
-	app := New(tmuxMock, fzfMock, sessionMock, false, "test")
-	app.Run("ls")
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	app.Run(parseArgs(t, "ls"))
 
 	if !sessionMock.listCalled {
 		t.Error("List should be called for builtin commands")
@@ -523,6 +1373,46 @@ func TestApp_Run_BuiltinsUseFullList(t *testing.T) {
 	}
 }
 
+func TestApp_Run_ListSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantExit int
+		want     string
+	}{
+		{name: "filters by source", args: []string{"ls", "--all", "--source", "smart", "--format", "{{.Name}}"}, want: "web\n"},
+		{name: "unknown source", args: []string{"ls", "--source", "config"}, wantExit: 1},
+		{name: "invalid format", args: []string{"ls", "--format", "{{.Name"}, wantExit: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionMock := &mockSessionFinder{listResult: []*session.Session{
+				{Name: "scratch", Exists: true, Source: session.SourceTmux},
+				{Name: "web", Source: session.SourceSmartDirectory},
+			}}
+
+			oldStdout, oldStderr := os.Stdout, os.Stderr
+			r, w, _ := os.Pipe()
+			os.Stdout, os.Stderr = w, w
+
+			app := New(&mockTmuxClient{available: true}, &mockFzfClient{}, &mockFzfClient{}, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+			got := app.Run(parseArgs(t, tt.args...))
+
+			w.Close()
+			os.Stdout, os.Stderr = oldStdout, oldStderr
+			output, _ := io.ReadAll(r)
+
+			if got != tt.wantExit {
+				t.Errorf("Run(%v) = %d, want %d", tt.args, got, tt.wantExit)
+			}
+			if tt.want != "" && string(output) != tt.want {
+				t.Errorf("Run(%v) output = %q, want %q", tt.args, output, tt.want)
+			}
+		})
+	}
+}
+
 func TestApp_Run_InsideTmux_PartialMatch(t *testing.T) {
 	tmuxMock := &mockTmuxClient{available: true, insideTmux: true, currentSession: "my-session"}
 	sessionMock := &mockSessionFinder{
@@ -530,8 +1420,8 @@ func TestApp_Run_InsideTmux_PartialMatch(t *testing.T) {
 	}
 	fzfMock := &mockFzfClient{available: false}
 
-	app := New(tmuxMock, fzfMock, sessionMock, false, "test")
-	app.Run("oth")
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	app.Run(parseArgs(t, "oth"))
 
 	if !sessionMock.listExcludingCalled {
 		t.Error("ListExcluding should be called when inside tmux with partial match")
@@ -546,8 +1436,8 @@ func TestApp_Run_Version(t *testing.T) {
 	sessionMock := &mockSessionFinder{}
 	fzfMock := &mockFzfClient{available: false}
 
-	app := New(tmuxMock, fzfMock, sessionMock, false, "1.2.3")
-	got := app.Run("version")
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{Version: "1.2.3"})
+	got := app.Run(parseArgs(t, "version"))
 
 	if got != 0 {
 		t.Errorf("Run(\"version\") = %d, want 0", got)
@@ -616,13 +1506,13 @@ func TestApp_Run_Status(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tmuxMock := &mockTmuxClient{available: tt.tmuxAvail, path: tt.tmuxPath, version: tt.tmuxVer}
 			fzfMock := &mockFzfClient{available: tt.fzfAvail, path: tt.fzfPath, version: tt.fzfVer}
-			app := New(tmuxMock, fzfMock, &mockSessionFinder{}, false, "test")
+			app := New(tmuxMock, fzfMock, fzfMock, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
 
 			oldStdout := os.Stdout
 			r, w, _ := os.Pipe()
 			os.Stdout = w
 
-			got := app.Run("status")
+			got := app.Run(parseArgs(t, "status"))
 
 			w.Close()
 			os.Stdout = oldStdout
@@ -642,13 +1532,183 @@ func TestApp_Run_Status(t *testing.T) {
 	}
 }
 
+func TestApp_Rank(t *testing.T) {
+	sessions := func() []*session.Session {
+		return []*session.Session{
+			{Name: "running", Exists: true},
+			{Name: "alpha"},
+			{Name: "daily"},
+			{Name: "weekly"},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		scores map[string]float64
+		want   []string
+	}{
+		{
+			name: "no history keeps finder order",
+			want: []string{"running", "alpha", "daily", "weekly"},
+		},
+		{
+			name:   "scored sessions first",
+			scores: map[string]float64{"daily": 12, "weekly": 3},
+			want:   []string{"daily", "weekly", "running", "alpha"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(&mockTmuxClient{}, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{scores: tt.scores}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+			var got []string
+			for _, s := range app.rank(sessions()) {
+				got = append(got, s.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("rank() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_Run_RanksFzfCandidates(t *testing.T) {
+	tmuxMock := &mockTmuxClient{available: true}
+	sessionMock := &mockSessionFinder{listResult: []*session.Session{{Name: "alpha"}, {Name: "daily"}}}
+	fzfMock := &mockFzfClient{available: true, selectResult: 0, selectOk: true}
+	historyMock := &mockHistoryStore{scores: map[string]float64{"daily": 4}}
+
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, historyMock, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	app.Run(parseArgs(t))
+
+	if len(fzfMock.providedItems) != 2 || !strings.HasPrefix(fzfMock.providedItems[0], "daily") {
+		t.Errorf("expected daily first, got %v", fzfMock.providedItems)
+	}
+	if tmuxMock.lastSession == nil || tmuxMock.lastSession.Name != "daily" {
+		t.Errorf("expected to attach to daily, got %+v", tmuxMock.lastSession)
+	}
+	if !slices.Equal(historyMock.recorded, []string{"daily"}) {
+		t.Errorf("expected attach to be recorded, got %v", historyMock.recorded)
+	}
+}
+
+func TestAppAttachToSession_RecordError(t *testing.T) {
+	tmuxMock := &mockTmuxClient{available: true}
+	historyMock := &mockHistoryStore{err: errors.New("read-only")}
+	app := New(tmuxMock, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, historyMock, &mockConfigFile{}, &mockPreviewer{}, Options{})
+
+	oldStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	err := app.attachToSession(&session.Session{Name: "api", Exists: true})
+
+	w.Close()
+	os.Stderr = oldStderr
+	out, _ := io.ReadAll(r)
+
+	if err != nil {
+		t.Fatalf("expected a history error not to stop the attach, got %v", err)
+	}
+	if !tmuxMock.attachSessionCalled {
+		t.Error("AttachSession should be called")
+	}
+	if !strings.Contains(string(out), "could not record history") {
+		t.Errorf("expected a warning, got %q", out)
+	}
+}
+
+func TestApp_Run_History(t *testing.T) {
+	existing := t.TempDir()
+	entries := func() []history.Entry {
+		return []history.Entry{
+			{Name: "api", Dir: existing, Count: 3, Score: 12},
+			{Name: "gone", Dir: existing + "/missing", Count: 1, Score: 1},
+			{Name: "web", Dir: existing, Count: 1, Score: 0.5},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		err         error
+		wantExit    int
+		wantOutput  []string
+		wantRemoved []string
+	}{
+		{
+			name:       "list",
+			args:       []string{"history"},
+			wantOutput: []string{"12.0", "api [" + existing + "]", "gone", "web"},
+		},
+		{
+			name:        "prune missing directories",
+			args:        []string{"history", "prune"},
+			wantOutput:  []string{"Removed gone"},
+			wantRemoved: []string{"gone"},
+		},
+		{
+			name:        "prune by name",
+			args:        []string{"history", "prune", "api", "web"},
+			wantOutput:  []string{"Removed api", "Removed web"},
+			wantRemoved: []string{"api", "web"},
+		},
+		{
+			name:     "unknown subcommand",
+			args:     []string{"history", "clear"},
+			wantExit: 1,
+		},
+		{
+			name:     "store error",
+			args:     []string{"history"},
+			err:      errors.New("history is corrupt"),
+			wantExit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyMock := &mockHistoryStore{entries: entries(), err: tt.err}
+			// History does not need tmux.
+			app := New(&mockTmuxClient{available: false}, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, historyMock, &mockConfigFile{}, &mockPreviewer{}, Options{})
+
+			oldStdout, oldStderr := os.Stdout, os.Stderr
+			r, w, _ := os.Pipe()
+			os.Stdout, os.Stderr = w, w
+
+			got := app.Run(parseArgs(t, tt.args...))
+
+			w.Close()
+			os.Stdout, os.Stderr = oldStdout, oldStderr
+			out, _ := io.ReadAll(r)
+			output := string(out)
+
+			if got != tt.wantExit {
+				t.Errorf("Run(%v) = %d, want %d", tt.args, got, tt.wantExit)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, output)
+				}
+			}
+			var removed []string
+			for _, e := range historyMock.pruned {
+				removed = append(removed, e.Name)
+			}
+			if !slices.Equal(removed, tt.wantRemoved) {
+				t.Errorf("pruned %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
 func TestApp_Run_TmuxUnavailable_ReturnsOne(t *testing.T) {
 	tmuxMock := &mockTmuxClient{available: false}
 	sessionMock := &mockSessionFinder{}
 	fzfMock := &mockFzfClient{available: false}
 
-	app := New(tmuxMock, fzfMock, sessionMock, false, "test")
-	got := app.Run("")
+	app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	got := app.Run(parseArgs(t))
 
 	if got != 1 {
 		t.Errorf("Run(\"\") = %d, want 1", got)
@@ -778,8 +1838,8 @@ func TestApp_Run_ErrorPaths(t *testing.T) {
 				selectOk:     tt.fzfOk,
 			}
 
-			app := New(tmuxMock, fzfMock, sessionMock, false, "test")
-			got := app.Run(tt.query)
+			app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+			got := app.Run(parseArgs(t, strings.Fields(tt.query)...))
 
 			if got != tt.wantExit {
 				t.Errorf("Run(%q) = %d, want %d", tt.query, got, tt.wantExit)
@@ -842,13 +1902,13 @@ func TestApp_Run_ErrorsGoToStderr(t *testing.T) {
 				selectError: tt.fzfError,
 			}
 
-			app := New(tmuxMock, fzfMock, sessionMock, false, "test")
+			app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
 
 			oldStderr := os.Stderr
 			r, w, _ := os.Pipe()
 			os.Stderr = w
 
-			app.Run(tt.query)
+			app.Run(parseArgs(t, strings.Fields(tt.query)...))
 
 			w.Close()
 			os.Stderr = oldStderr
@@ -885,7 +1945,7 @@ func TestApp_DebugMsg(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			app := New(&mockTmuxClient{}, &mockFzfClient{}, &mockSessionFinder{}, tt.debug, "test")
+			app := New(&mockTmuxClient{}, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{Debug: tt.debug})
 
 			oldStdout := os.Stdout
 			r, w, _ := os.Pipe()
@@ -946,6 +2006,11 @@ func TestFormatSessionLine(t *testing.T) {
 			session:  &session.Session{Name: "myapp", Dir: "/home/user/myapp", Exists: true, Aliases: []string{"ma"}},
 			expected: "myapp (ma) [/home/user/myapp] *",
 		},
+		{
+			name:     "with source",
+			session:  &session.Session{Name: "myapp", Dir: "/home/user/myapp", Exists: true, Source: session.SourceSmartDirectory},
+			expected: "myapp [/home/user/myapp] * <smart>",
+		},
 	}
 
 	for _, tt := range tests {
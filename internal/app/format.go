@@ -0,0 +1,133 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/griggsjared/tm/internal/session"
+)
+
+// sessionRecord is the machine-readable form of a session, shared by every
+// --format so scripts see the same fields whichever they pick.
+type sessionRecord struct {
+	Name         string         `json:"name"`
+	Dir          string         `json:"dir"`
+	Exists       bool           `json:"exists"`
+	LastAttached int64          `json:"last_attached"`
+	Aliases      []string       `json:"aliases"`
+	Source       session.Source `json:"source"`
+	Root         string         `json:"root"`
+	Socket       string         `json:"socket"`
+}
+
+func newSessionRecord(s *session.Session) sessionRecord {
+	aliases := s.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+	return sessionRecord{
+		Name:         s.Name,
+		Dir:          s.Dir,
+		Exists:       s.Exists,
+		LastAttached: s.LastAttached,
+		Aliases:      aliases,
+		Source:       s.Source,
+		Root:         s.Root,
+		Socket:       s.Socket,
+	}
+}
+
+// writeSessions writes sessions to w in format: empty for the human-readable
+// list, json, tsv, or otherwise a Go template executed once per session.
+func writeSessions(w io.Writer, sessions []*session.Session, format string) error {
+	records := make([]sessionRecord, len(sessions))
+	for i, s := range sessions {
+		records[i] = newSessionRecord(s)
+	}
+
+	switch format {
+	case "":
+		for _, s := range sessions {
+			fmt.Fprintln(w, formatSessionLine(s))
+		}
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "tsv":
+		for _, r := range records {
+			fields := []string{
+				r.Name,
+				r.Dir,
+				strconv.FormatBool(r.Exists),
+				strconv.FormatInt(r.LastAttached, 10),
+				strings.Join(r.Aliases, ","),
+				string(r.Source),
+				r.Root,
+				r.Socket,
+			}
+			for i, f := range fields {
+				fields[i] = escapeTSV(f)
+			}
+			fmt.Fprintln(w, strings.Join(fields, "\t"))
+		}
+		return nil
+	}
+
+	tmpl, err := template.New("format").Funcs(template.FuncMap{"join": strings.Join}).Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid format: %w", err)
+	}
+	for _, r := range records {
+		if err := tmpl.Execute(w, r); err != nil {
+			return fmt.Errorf("invalid format: %w", err)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// escapeTSV escapes the characters that would break a TSV line, so a
+// directory name can never shift or split the columns.
+func escapeTSV(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+// parseSources parses a comma-separated list of session sources, as given
+// to --source. An empty value selects every source.
+func parseSources(value string) ([]session.Source, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var sources []session.Source
+	for name := range strings.SplitSeq(value, ",") {
+		source := session.Source(strings.TrimSpace(name))
+		if !slices.Contains(session.Sources, source) {
+			return nil, fmt.Errorf("unknown source %q, want tmux, pre-defined or smart", name)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// filterSources keeps the sessions from one of sources, or every session
+// when sources is empty.
+func filterSources(sessions []*session.Session, sources []session.Source) []*session.Session {
+	if len(sources) == 0 {
+		return sessions
+	}
+	var filtered []*session.Session
+	for _, s := range sessions {
+		if slices.Contains(sources, s.Source) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
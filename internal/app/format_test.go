@@ -0,0 +1,151 @@
+package app
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/griggsjared/tm/internal/session"
+)
+
+func TestWriteSessions(t *testing.T) {
+	sessions := []*session.Session{
+		{Name: "api", Dir: "/src/[work]/api", Exists: true, LastAttached: 1700000000, Aliases: []string{"be", "backend"}, Source: session.SourcePreDefined, Socket: "work"},
+		{Name: "notes", Dir: "/home/me/my\tnotes", Source: session.SourceSmartDirectory, Root: "/home/me"},
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "human readable",
+			format: "",
+			want:   "api (be, backend) [/src/[work]/api] * @work <pre-defined>\nnotes [/home/me/my\tnotes] <smart>\n",
+		},
+		{
+			name:   "json",
+			format: "json",
+			want: `[
+  {
+    "name": "api",
+    "dir": "/src/[work]/api",
+    "exists": true,
+    "last_attached": 1700000000,
+    "aliases": [
+      "be",
+      "backend"
+    ],
+    "source": "pre-defined",
+    "root": "",
+    "socket": "work"
+  },
+  {
+    "name": "notes",
+    "dir": "/home/me/my\tnotes",
+    "exists": false,
+    "last_attached": 0,
+    "aliases": [],
+    "source": "smart",
+    "root": "/home/me",
+    "socket": ""
+  }
+]
+`,
+		},
+		{
+			name:   "tsv escapes tabs",
+			format: "tsv",
+			want:   "api\t/src/[work]/api\ttrue\t1700000000\tbe,backend\tpre-defined\t\twork\nnotes\t/home/me/my\\tnotes\tfalse\t0\t\tsmart\t/home/me\t\n",
+		},
+		{
+			name:   "template",
+			format: `{{.Name}} {{.Source}} {{join .Aliases ","}}{{if .Exists}} *{{end}}`,
+			want:   "api pre-defined be,backend *\nnotes smart \n",
+		},
+		{
+			name:    "invalid template",
+			format:  "{{.Name",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			format:  "{{.Nope}}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeSessions(&buf, sessions, tt.format)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid format") {
+					t.Fatalf("expected invalid format error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("writeSessions(%q) =\n%s\nwant:\n%s", tt.format, buf.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("json without sessions is an empty list", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeSessions(&buf, nil, "json"); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "[]\n" {
+			t.Errorf("expected [], got %q", buf.String())
+		}
+	})
+}
+
+func TestParseSources(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []session.Source
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "smart", want: []session.Source{session.SourceSmartDirectory}},
+		{value: "tmux, pre-defined", want: []session.Source{session.SourceTmux, session.SourcePreDefined}},
+		{value: "config", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSources(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSources(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseSources(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterSources(t *testing.T) {
+	sessions := []*session.Session{
+		{Name: "scratch", Source: session.SourceTmux},
+		{Name: "api", Source: session.SourcePreDefined},
+		{Name: "web", Source: session.SourceSmartDirectory},
+	}
+
+	if got := filterSources(sessions, nil); len(got) != 3 {
+		t.Errorf("expected every session without sources, got %d", len(got))
+	}
+
+	got := filterSources(sessions, []session.Source{session.SourceTmux, session.SourceSmartDirectory})
+	if len(got) != 2 || got[0].Name != "scratch" || got[1].Name != "web" {
+		t.Errorf("unexpected filtered sessions %v", got)
+	}
+}
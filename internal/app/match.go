@@ -0,0 +1,171 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/griggsjared/tm/internal/session"
+)
+
+// Matcher resolves a query to sessions without the picker. The zero value
+// matches names and aliases by prefix. Ranked also matches words inside
+// them, substrings and subsequences, best first, and Dirs matches the
+// session directory as well.
+type Matcher struct {
+	Ranked bool
+	Dirs   bool
+}
+
+// How well a query matches a name, best last.
+const (
+	tierNone = iota
+	tierSubsequence
+	tierSubstring
+	tierWord
+	tierPrefix
+)
+
+type rankedSession struct {
+	session *session.Session
+	tier    int
+}
+
+// Filter returns the sessions matching query, best first. Sessions that
+// match equally well keep their order.
+func (m Matcher) Filter(sessions []*session.Session, query string) []*session.Session {
+	if !m.Ranked {
+		return m.filterPrefix(sessions, query)
+	}
+	ranked := m.rank(sessions, query)
+	matches := make([]*session.Session, len(ranked))
+	for i, r := range ranked {
+		matches[i] = r.session
+	}
+	return matches
+}
+
+// Best returns the session query clearly points at: the only match, or with
+// Ranked a match in a better tier than every other. It returns nil when the
+// user has to choose.
+func (m Matcher) Best(sessions []*session.Session, query string) *session.Session {
+	if query == "" {
+		return nil
+	}
+	if !m.Ranked {
+		if matches := m.filterPrefix(sessions, query); len(matches) == 1 {
+			return matches[0]
+		}
+		return nil
+	}
+
+	ranked := m.rank(sessions, query)
+	switch {
+	case len(ranked) == 0:
+		return nil
+	case len(ranked) == 1 || ranked[0].tier > ranked[1].tier:
+		return ranked[0].session
+	}
+	return nil
+}
+
+func (m Matcher) filterPrefix(sessions []*session.Session, query string) []*session.Session {
+	if query == "" {
+		return sessions
+	}
+	var matches []*session.Session
+	for _, s := range sessions {
+		if m.tier(s, query, func(c, q string) int {
+			if strings.HasPrefix(strings.ToLower(c), strings.ToLower(q)) {
+				return tierPrefix
+			}
+			return tierNone
+		}) != tierNone {
+			matches = append(matches, s)
+		}
+	}
+	return matches
+}
+
+func (m Matcher) rank(sessions []*session.Session, query string) []rankedSession {
+	var ranked []rankedSession
+	for _, s := range sessions {
+		if query == "" {
+			ranked = append(ranked, rankedSession{session: s})
+			continue
+		}
+		if t := m.tier(s, query, matchTier); t != tierNone {
+			ranked = append(ranked, rankedSession{session: s, tier: t})
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b rankedSession) int {
+		return b.tier - a.tier
+	})
+	return ranked
+}
+
+// tier is the best tier of the session name, its aliases and, with Dirs,
+// its directory.
+func (m Matcher) tier(s *session.Session, query string, match func(candidate, query string) int) int {
+	best := match(s.Name, query)
+	for _, alias := range s.Aliases {
+		best = max(best, match(alias, query))
+	}
+	if m.Dirs && s.Dir != "" {
+		best = max(best, match(s.Dir, query))
+	}
+	return best
+}
+
+// matchTier compares case-insensitively, but looks at the original case to
+// find words in camelCase names.
+func matchTier(candidate, query string) int {
+	c, q := strings.ToLower(candidate), strings.ToLower(query)
+	if strings.HasPrefix(c, q) {
+		return tierPrefix
+	}
+
+	if i := strings.Index(c, q); i >= 0 {
+		original := []rune(candidate)
+		for ; i >= 0; i = nextIndex(c, q, i) {
+			n := len([]rune(c[:i]))
+			if n < len(original) && startsWord(original, n) {
+				return tierWord
+			}
+		}
+		return tierSubstring
+	}
+
+	rest := []rune(q)
+	for _, r := range c {
+		if len(rest) > 0 && r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	if len(rest) == 0 {
+		return tierSubsequence
+	}
+	return tierNone
+}
+
+// nextIndex returns the index of the next q in c after the one at i, or -1.
+func nextIndex(c, q string, i int) int {
+	j := strings.Index(c[i+1:], q)
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+// startsWord reports whether the rune at i follows a separator such as - or
+// /, or is an upper case letter after a lower case one.
+func startsWord(r []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := r[i-1]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(r[i])
+}
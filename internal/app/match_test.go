@@ -0,0 +1,150 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/griggsjared/tm/internal/session"
+)
+
+func TestMatchTier(t *testing.T) {
+	tests := []struct {
+		candidate string
+		query     string
+		want      int
+	}{
+		{candidate: "api", query: "ap", want: tierPrefix},
+		{candidate: "API", query: "api", want: tierPrefix},
+		{candidate: "billing-api", query: "api", want: tierWord},
+		{candidate: "/src/billing", query: "bill", want: tierWord},
+		{candidate: "billingApi", query: "api", want: tierWord},
+		{candidate: "rapid-api", query: "api", want: tierWord},
+		{candidate: "rapid", query: "api", want: tierSubstring},
+		{candidate: "billing-api", query: "bap", want: tierSubsequence},
+		{candidate: "api", query: "pa", want: tierNone},
+		{candidate: "api", query: "apis", want: tierNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.candidate+"/"+tt.query, func(t *testing.T) {
+			if got := matchTier(tt.candidate, tt.query); got != tt.want {
+				t.Errorf("matchTier(%q, %q) = %d, want %d", tt.candidate, tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher_Filter(t *testing.T) {
+	sessions := []*session.Session{
+		{Name: "rapid", Dir: "/src/rapid"},
+		{Name: "billing-api", Dir: "/src/billing"},
+		{Name: "api", Dir: "/src/api"},
+		{Name: "docs", Dir: "/src/api-docs"},
+	}
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		query   string
+		want    []string
+	}{
+		{name: "prefix", query: "api", want: []string{"api"}},
+		{name: "prefix with dirs", matcher: Matcher{Dirs: true}, query: "/src/api", want: []string{"api", "docs"}},
+		{name: "ranked best first", matcher: Matcher{Ranked: true}, query: "api", want: []string{"api", "billing-api", "rapid"}},
+		{name: "ranked with dirs", matcher: Matcher{Ranked: true, Dirs: true}, query: "api", want: []string{"api", "billing-api", "docs", "rapid"}},
+		{name: "ranked subsequence", matcher: Matcher{Ranked: true}, query: "bap", want: []string{"billing-api"}},
+		{name: "empty query keeps everything", matcher: Matcher{Ranked: true}, query: "", want: []string{"rapid", "billing-api", "api", "docs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.matcher.Filter(sessions, tt.query)
+			var names []string
+			for _, s := range got {
+				names = append(names, s.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("Filter(%q) = %v, want %v", tt.query, names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Fatalf("Filter(%q) = %v, want %v", tt.query, names, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatcher_Best(t *testing.T) {
+	sessions := []*session.Session{
+		{Name: "rapid"},
+		{Name: "billing-api", Aliases: []string{"bill"}},
+		{Name: "api"},
+		{Name: "app"},
+	}
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		query   string
+		want    string
+	}{
+		{name: "prefix single match", query: "api", want: "api"},
+		{name: "prefix several matches", query: "ap"},
+		{name: "prefix misses words", query: "bap"},
+		{name: "ranked prefix beats word", matcher: Matcher{Ranked: true}, query: "api", want: "api"},
+		{name: "ranked tie", matcher: Matcher{Ranked: true}, query: "ap"},
+		{name: "ranked subsequence", matcher: Matcher{Ranked: true}, query: "bap", want: "billing-api"},
+		{name: "ranked alias", matcher: Matcher{Ranked: true}, query: "bil", want: "billing-api"},
+		{name: "ranked no match", matcher: Matcher{Ranked: true}, query: "zzz"},
+		{name: "empty query", matcher: Matcher{Ranked: true}, query: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.matcher.Best(sessions, tt.query)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("Best(%q) = %s, want none", tt.query, got.Name)
+				}
+				return
+			}
+			if got == nil || got.Name != tt.want {
+				t.Errorf("Best(%q) = %v, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApp_Run_RankedQuery(t *testing.T) {
+	sessions := []*session.Session{{Name: "billing-api", Dir: "/src/billing", Exists: true}, {Name: "web", Dir: "/src/web", Exists: true}}
+
+	tests := []struct {
+		name         string
+		matcher      Matcher
+		wantAttached bool
+	}{
+		{name: "prefix needs the picker", matcher: Matcher{}},
+		{name: "ranked attaches", matcher: Matcher{Ranked: true}, wantAttached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmuxMock := &mockTmuxClient{available: true}
+			fzfMock := &mockFzfClient{available: true}
+
+			app := New(tmuxMock, fzfMock, fzfMock, &mockSessionFinder{listResult: sessions}, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{Matcher: tt.matcher})
+			if got := app.Run(parseArgs(t, "bap")); got != 0 {
+				t.Fatalf("Run(bap) = %d, want 0", got)
+			}
+			if tmuxMock.attachSessionCalled != tt.wantAttached {
+				t.Errorf("attached = %v, want %v", tmuxMock.attachSessionCalled, tt.wantAttached)
+			}
+			if fzfMock.selectCalled == tt.wantAttached {
+				t.Errorf("picker opened = %v, want %v", fzfMock.selectCalled, !tt.wantAttached)
+			}
+			if tt.wantAttached && tmuxMock.lastSession.Name != "billing-api" {
+				t.Errorf("attached %s, want billing-api", tmuxMock.lastSession.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/griggsjared/tm/internal/fzf"
+	"github.com/griggsjared/tm/internal/session"
+)
+
+// Keys that act on the highlighted session in the open picker instead of
+// opening it.
+const (
+	keyKill   = "ctrl-x"
+	keyRename = "ctrl-r"
+	keyNew    = "ctrl-n"
+	keyWindow = "ctrl-o"
+)
+
+var pickerKeys = []string{keyKill, keyRename, keyNew, keyWindow}
+
+const pickerHeader = "enter: open  ctrl-o: open in window  ctrl-n: new from query  ctrl-r: rename  ctrl-x: kill"
+
+// pick runs the open picker over the sessions returned by list until the
+// user opens one, creates one or gives up. Killing and renaming a session
+// bring the picker back with a fresh list and the query typed so far.
+func (a *App) pick(list func() []*session.Session, query string) error {
+	for {
+		sessions := list()
+		if len(sessions) == 0 {
+			printNoSessions(query)
+			return nil
+		}
+		if !a.picker.IsAvailable() {
+			printAvailableSessions(sessions, query)
+			return nil
+		}
+
+		sel, ok, err := a.selectAction(sessions, query)
+		if err != nil || !ok {
+			return err
+		}
+		query = sel.Query
+
+		var target *session.Session
+		if sel.Index >= 0 {
+			target = sessions[sel.Index]
+		}
+
+		switch sel.Key {
+		case keyNew:
+			return a.newSessionFromQuery(sel.Query)
+		case keyWindow:
+			if target == nil {
+				return nil
+			}
+			return a.openInWindow(target)
+		case keyKill:
+			if target != nil {
+				a.reportPickerError(a.killFromPicker(target))
+			}
+		case keyRename:
+			if target != nil {
+				a.reportPickerError(a.renameFromPicker(target))
+			}
+		default:
+			if target == nil {
+				return nil
+			}
+			return a.attachToSession(target)
+		}
+	}
+}
+
+func (a *App) selectAction(sessions []*session.Session, query string) (fzf.Selection, bool, error) {
+	items := make([]string, len(sessions))
+	for i, s := range sessions {
+		items[i] = formatSessionLine(s)
+	}
+
+	opts, cleanup := a.previewOptions(sessions)
+	defer cleanup()
+	opts.Header = pickerHeader
+	opts.Expect = pickerKeys
+
+	return a.picker.Select(items, query, opts)
+}
+
+// reportPickerError prints an error from an action that returns to the
+// picker, so the user can try again instead of tm exiting.
+func (a *App) reportPickerError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+}
+
+func (a *App) killFromPicker(s *session.Session) error {
+	if !s.Exists {
+		return fmt.Errorf("%s is not running", s.Name)
+	}
+	return a.confirmKill([]*session.Session{s})
+}
+
+func (a *App) renameFromPicker(s *session.Session) error {
+	if !s.Exists {
+		return fmt.Errorf("%s is not running", s.Name)
+	}
+	name, err := a.prompt(fmt.Sprintf("New name for %s:", s.Name))
+	if err != nil {
+		return nil
+	}
+	return a.renameSession(s, name)
+}
+
+// newSessionFromQuery creates a session named after the query in the
+// current directory and opens it.
+func (a *App) newSessionFromQuery(query string) error {
+	name := strings.TrimSpace(query)
+	if name == "" {
+		return fmt.Errorf("type a name for the new session first")
+	}
+	if err := a.checkNewName(&session.Session{}, name); err != nil {
+		return err
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current directory: %w", err)
+	}
+
+	s := session.New(name, dir, false, 0)
+	s.Source = session.SourceTmux
+	return a.attachToSession(s)
+}
+
+// openInWindow opens the project of s in a new window of the current
+// session instead of switching to s.
+func (a *App) openInWindow(s *session.Session) error {
+	current := a.currentSession()
+	if current == "" {
+		return fmt.Errorf("opening in a window only works inside tmux")
+	}
+	if s.Dir == "" {
+		return fmt.Errorf("%s has no directory to open", s.Name)
+	}
+
+	a.debugMsg(fmt.Sprintf("Opening %s in a new window of %s", s.Dir, current))
+	if err := a.tmuxClient.NewWindow(&session.Session{Name: current}, s.Name, s.Dir); err != nil {
+		return fmt.Errorf("error opening window: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/griggsjared/tm/internal/fzf"
+	"github.com/griggsjared/tm/internal/session"
+)
+
+func TestApp_Run_PickerActions(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := func() []*session.Session {
+		return []*session.Session{
+			{Name: "api", Dir: "/src/api", Exists: true},
+			{Name: "web", Dir: "/src/web"},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		actions        []fzf.Selection
+		insideTmux     bool
+		stdin          string
+		wantExit       int
+		wantSelects    int
+		wantKilled     []string
+		wantRenamed    []string
+		wantCreated    string
+		wantNewWindows []string
+	}{
+		{
+			name:        "enter opens the highlighted session",
+			actions:     []fzf.Selection{{Index: 1}},
+			wantSelects: 1,
+			wantCreated: "web",
+		},
+		{
+			name:        "kill asks and returns to the picker",
+			actions:     []fzf.Selection{{Index: 0, Key: keyKill, Query: "a"}},
+			stdin:       "y\n",
+			wantSelects: 2,
+			wantKilled:  []string{"api"},
+		},
+		{
+			name:        "kill keeps the session when not confirmed",
+			actions:     []fzf.Selection{{Index: 0, Key: keyKill}},
+			stdin:       "n\n",
+			wantSelects: 2,
+		},
+		{
+			name:        "kill refuses a session that is not running",
+			actions:     []fzf.Selection{{Index: 1, Key: keyKill}},
+			wantSelects: 2,
+		},
+		{
+			name:        "rename asks for the new name and returns to the picker",
+			actions:     []fzf.Selection{{Index: 0, Key: keyRename}},
+			stdin:       "backend\n",
+			wantSelects: 2,
+			wantRenamed: []string{"api -> backend"},
+		},
+		{
+			name:        "rename refuses a name in use",
+			actions:     []fzf.Selection{{Index: 0, Key: keyRename}},
+			stdin:       "web\n",
+			wantSelects: 2,
+		},
+		{
+			name:        "new creates a session from the query in the current directory",
+			actions:     []fzf.Selection{{Index: -1, Key: keyNew, Query: "scratch"}},
+			wantSelects: 1,
+			wantCreated: "scratch",
+		},
+		{
+			name:        "new needs a query",
+			actions:     []fzf.Selection{{Index: 0, Key: keyNew, Query: " "}},
+			wantExit:    1,
+			wantSelects: 1,
+		},
+		{
+			name:        "new refuses a name in use",
+			actions:     []fzf.Selection{{Index: 0, Key: keyNew, Query: "web"}},
+			wantExit:    1,
+			wantSelects: 1,
+		},
+		{
+			name:           "window opens the project in the current session",
+			actions:        []fzf.Selection{{Index: 1, Key: keyWindow}},
+			insideTmux:     true,
+			wantSelects:    1,
+			wantNewWindows: []string{"current: web /src/web"},
+		},
+		{
+			name:        "window needs tmux",
+			actions:     []fzf.Selection{{Index: 1, Key: keyWindow}},
+			wantExit:    1,
+			wantSelects: 1,
+		},
+		{
+			name:        "action with nothing highlighted",
+			actions:     []fzf.Selection{{Index: -1, Key: keyWindow}},
+			insideTmux:  true,
+			wantSelects: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmuxMock := &mockTmuxClient{available: true, insideTmux: tt.insideTmux, currentSession: "current"}
+			fzfMock := &mockFzfClient{available: true, actions: tt.actions}
+			sessionMock := &mockSessionFinder{listResult: sessions()}
+
+			oldStdin, oldStdout, oldStderr := os.Stdin, os.Stdout, os.Stderr
+			r, w, _ := os.Pipe()
+			w.WriteString(tt.stdin)
+			w.Close()
+			os.Stdin = r
+			_, out, _ := os.Pipe()
+			os.Stdout, os.Stderr = out, out
+			defer func() { os.Stdin, os.Stdout, os.Stderr = oldStdin, oldStdout, oldStderr }()
+
+			app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+			got := app.Run(parseArgs(t))
+
+			if got != tt.wantExit {
+				t.Errorf("Run() = %d, want %d", got, tt.wantExit)
+			}
+			if fzfMock.selectCalls != tt.wantSelects {
+				t.Errorf("picker opened %d times, want %d", fzfMock.selectCalls, tt.wantSelects)
+			}
+			if !slices.Equal(tmuxMock.killed, tt.wantKilled) {
+				t.Errorf("killed %v, want %v", tmuxMock.killed, tt.wantKilled)
+			}
+			if !slices.Equal(tmuxMock.renamed, tt.wantRenamed) {
+				t.Errorf("renamed %v, want %v", tmuxMock.renamed, tt.wantRenamed)
+			}
+			if !slices.Equal(tmuxMock.newWindows, tt.wantNewWindows) {
+				t.Errorf("new windows %v, want %v", tmuxMock.newWindows, tt.wantNewWindows)
+			}
+			if tt.wantCreated == "" {
+				if tmuxMock.newSessionCalled {
+					t.Errorf("expected no session to be created, got %+v", tmuxMock.lastSession)
+				}
+			} else {
+				if !tmuxMock.newSessionCalled || tmuxMock.lastSession.Name != tt.wantCreated {
+					t.Fatalf("expected %s to be created, got %+v", tt.wantCreated, tmuxMock.lastSession)
+				}
+				if tt.wantCreated == "scratch" && tmuxMock.lastSession.Dir != cwd {
+					t.Errorf("created in %s, want %s", tmuxMock.lastSession.Dir, cwd)
+				}
+			}
+		})
+	}
+}
+
+func TestApp_Run_PickerOptions(t *testing.T) {
+	fzfMock := &mockFzfClient{available: true, actions: []fzf.Selection{}}
+	sessionMock := &mockSessionFinder{listResult: []*session.Session{{Name: "api", Dir: "/src/api"}}}
+
+	app := New(&mockTmuxClient{available: true}, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	if got := app.Run(parseArgs(t)); got != 0 {
+		t.Fatalf("Run() = %d, want 0", got)
+	}
+
+	if fzfMock.providedOpts.Header != pickerHeader {
+		t.Errorf("header = %q, want %q", fzfMock.providedOpts.Header, pickerHeader)
+	}
+	if !slices.Equal(fzfMock.providedOpts.Expect, pickerKeys) {
+		t.Errorf("expect = %v, want %v", fzfMock.providedOpts.Expect, pickerKeys)
+	}
+}
+
+func TestApp_Run_PickerWithoutFzf(t *testing.T) {
+	tmuxMock := &mockTmuxClient{available: true}
+	pickerMock := &mockFzfClient{available: true, actions: []fzf.Selection{{Index: 0}}}
+	sessionMock := &mockSessionFinder{listResult: []*session.Session{{Name: "api", Dir: "/src/api", Exists: true}}}
+
+	app := New(tmuxMock, &mockFzfClient{}, pickerMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+	if got := app.Run(parseArgs(t)); got != 0 {
+		t.Fatalf("Run() = %d, want 0", got)
+	}
+	if pickerMock.selectCalls != 1 {
+		t.Errorf("picker opened %d times, want 1", pickerMock.selectCalls)
+	}
+	if !tmuxMock.attachSessionCalled {
+		t.Error("expected the picked session to be attached")
+	}
+}
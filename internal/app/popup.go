@@ -0,0 +1,68 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/griggsjared/tm/internal/shell"
+)
+
+// popupEnv is set for the tm running inside the popup, so it shows the
+// picker in place instead of opening another popup.
+const popupEnv = "TM_POPUP"
+
+// Popup is the size and border of the tmux popup tm popup opens. Always
+// opens it for tm without a query as well.
+type Popup struct {
+	Width  string
+	Height string
+	Border string
+	Always bool
+}
+
+func insidePopup() bool {
+	return os.Getenv(popupEnv) != ""
+}
+
+// runPopup opens the picker in a tmux popup, or right here when tm already
+// runs in one.
+func (a *App) runPopup(query, currentSession string) error {
+	if insidePopup() {
+		return a.runOpen(query, currentSession)
+	}
+	if !a.tmuxClient.InsideTmux() {
+		return fmt.Errorf("tm popup only works inside tmux")
+	}
+	return a.openPopup(query)
+}
+
+// openPopup runs tm again in a popup over the current pane, with the same
+// config file, socket and query.
+func (a *App) openPopup(query string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error finding tm: %w", err)
+	}
+
+	args := []string{popupEnv + "=1", shell.Quote(exe), "--config", shell.Quote(a.configFile.Path())}
+	args = append(args, a.socketFlags()...)
+	if a.debug {
+		args = append(args, "--debug")
+	}
+	args = append(args, "--")
+	if query != "" {
+		args = append(args, shell.Quote(query))
+	}
+	command := strings.Join(args, " ")
+
+	// The popup starts in the current directory, so a session created from
+	// the query there is where the user ran tm.
+	dir, _ := os.Getwd()
+
+	a.debugMsg(fmt.Sprintf("Opening popup: %s", command))
+	if err := a.tmuxClient.DisplayPopup(a.popup.Width, a.popup.Height, a.popup.Border, dir, command); err != nil {
+		return fmt.Errorf("error opening popup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/griggsjared/tm/internal/fzf"
+	"github.com/griggsjared/tm/internal/session"
+	"github.com/griggsjared/tm/internal/shell"
+	"github.com/griggsjared/tm/internal/tmux"
+)
+
+func TestApp_Run_Popup(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	popup := Popup{Width: "80%", Height: "60%", Border: "rounded"}
+
+	tests := []struct {
+		name        string
+		args        []string
+		popup       Popup
+		insideTmux  bool
+		insidePopup bool
+		socket      tmux.Socket
+		popupError  error
+		wantExit    int
+		wantPopup   string
+		wantPicker  bool
+	}{
+		{
+			name:       "opens a popup",
+			args:       []string{"popup"},
+			popup:      popup,
+			insideTmux: true,
+			wantPopup:  "80%|60%|rounded|" + cwd + "|TM_POPUP=1 " + shell.Quote(exe) + " --config '/home/me/.config/tm/config.yaml' --",
+		},
+		{
+			name:       "passes the query and socket",
+			args:       []string{"popup", "it's"},
+			popup:      popup,
+			insideTmux: true,
+			socket:     tmux.Socket{Name: "work"},
+			wantPopup:  "80%|60%|rounded|" + cwd + "|TM_POPUP=1 " + shell.Quote(exe) + ` --config '/home/me/.config/tm/config.yaml' --socket-name 'work' -- 'it'\''s'`,
+		},
+		{
+			name:     "needs tmux",
+			args:     []string{"popup"},
+			popup:    popup,
+			wantExit: 1,
+		},
+		{
+			name:       "popup error",
+			args:       []string{"popup"},
+			popup:      popup,
+			insideTmux: true,
+			popupError: errors.New("unknown command: display-popup"),
+			wantExit:   1,
+			wantPopup:  "80%|60%|rounded|" + cwd + "|TM_POPUP=1 " + shell.Quote(exe) + " --config '/home/me/.config/tm/config.yaml' --",
+		},
+		{
+			name:        "inside a popup picks in place",
+			args:        []string{"popup"},
+			popup:       popup,
+			insideTmux:  true,
+			insidePopup: true,
+			wantPicker:  true,
+		},
+		{
+			name:       "always opens tm without a query in a popup",
+			popup:      Popup{Always: true},
+			insideTmux: true,
+			wantPopup:  "|||" + cwd + "|TM_POPUP=1 " + shell.Quote(exe) + " --config '/home/me/.config/tm/config.yaml' --",
+		},
+		{
+			name:       "always leaves queries alone",
+			args:       []string{"ap"},
+			popup:      Popup{Always: true},
+			insideTmux: true,
+			wantPicker: true,
+		},
+		{
+			name:       "always needs tmux",
+			popup:      Popup{Always: true},
+			wantPicker: true,
+		},
+		{
+			name:        "always does not nest",
+			popup:       Popup{Always: true},
+			insideTmux:  true,
+			insidePopup: true,
+			wantPicker:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.insidePopup {
+				t.Setenv(popupEnv, "1")
+			} else {
+				t.Setenv(popupEnv, "")
+			}
+
+			tmuxMock := &mockTmuxClient{available: true, insideTmux: tt.insideTmux, currentSession: "current", socket: tt.socket, popupError: tt.popupError}
+			fzfMock := &mockFzfClient{available: true, actions: []fzf.Selection{}}
+			sessionMock := &mockSessionFinder{listResult: []*session.Session{{Name: "api"}, {Name: "app"}}}
+
+			oldStderr := os.Stderr
+			_, errOut, _ := os.Pipe()
+			os.Stderr = errOut
+			defer func() { os.Stderr = oldStderr }()
+
+			app := New(tmuxMock, fzfMock, fzfMock, sessionMock, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{Popup: tt.popup})
+			got := app.Run(parseArgs(t, tt.args...))
+
+			if got != tt.wantExit {
+				t.Errorf("Run(%v) = %d, want %d", tt.args, got, tt.wantExit)
+			}
+			if gotPopup := strings.Join(tmuxMock.popups, "\n"); gotPopup != tt.wantPopup {
+				t.Errorf("popup = %q, want %q", gotPopup, tt.wantPopup)
+			}
+			if fzfMock.selectCalled != tt.wantPicker {
+				t.Errorf("picker opened = %v, want %v", fzfMock.selectCalled, tt.wantPicker)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/griggsjared/tm/internal/fzf"
+	"github.com/griggsjared/tm/internal/session"
+	"github.com/griggsjared/tm/internal/shell"
+)
+
+// previewOptions writes the picker candidates to a temporary file and
+// returns fzf options that preview the highlighted one by running
+// tm preview with its index, the same config file and socket. The returned
+// func removes the file. When the file cannot be written the picker works
+// as before, without a preview.
+func (a *App) previewOptions(sessions []*session.Session) (fzf.Options, func()) {
+	noPreview := func() {}
+
+	exe, err := os.Executable()
+	if err != nil {
+		a.debugMsg(fmt.Sprintf("Disabling preview: %v", err))
+		return fzf.Options{}, noPreview
+	}
+
+	path, err := writePreviewList(a.cacheDir, sessions)
+	if err != nil {
+		a.debugMsg(fmt.Sprintf("Disabling preview: %v", err))
+		return fzf.Options{}, noPreview
+	}
+
+	args := []string{shell.Quote(exe), "--config", shell.Quote(a.configFile.Path())}
+	args = append(args, a.socketFlags()...)
+	args = append(args, "preview", "--list", shell.Quote(path), "{1}")
+
+	return fzf.Options{Preview: strings.Join(args, " ")}, func() { os.Remove(path) }
+}
+
+// writePreviewList writes the candidates to a new file in dir, or in the
+// temporary directory when dir is empty.
+func writePreviewList(dir string, sessions []*session.Session) (string, error) {
+	records := make([]sessionRecord, len(sessions))
+	for i, s := range sessions {
+		records[i] = newSessionRecord(s)
+	}
+	content, err := json.Marshal(records)
+	if err != nil {
+		return "", err
+	}
+
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+	f, err := os.CreateTemp(dir, "tm-preview-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create preview list: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(content); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write preview list: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// runPreview prints what fzf shows next to the highlighted candidate: the
+// windows and active pane of a running session, or the project directory of
+// one that is not running.
+func (a *App) runPreview(listPath, index string) int {
+	s, err := readPreviewCandidate(listPath, index)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	if s.Exists && a.tmuxClient.IsAvailable() {
+		if windows, err := a.tmuxClient.ListWindows(s); err == nil {
+			fmt.Print(windows)
+			if pane, err := a.tmuxClient.CapturePane(s); err == nil && pane != "" {
+				fmt.Println()
+				fmt.Print(pane)
+			}
+			return 0
+		}
+	}
+
+	if s.Dir == "" {
+		return 0
+	}
+	if err := a.previewer.Project(os.Stdout, s.Dir); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+// readPreviewCandidate returns the candidate at the 1-based index in the
+// list written by writePreviewList.
+func readPreviewCandidate(listPath, index string) (*session.Session, error) {
+	if listPath == "" {
+		return nil, fmt.Errorf("preview needs --list")
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		return nil, fmt.Errorf("invalid preview index %q", index)
+	}
+
+	content, err := os.ReadFile(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("preview list is inaccessible: %w", err)
+	}
+	var records []sessionRecord
+	if err := json.Unmarshal(content, &records); err != nil {
+		return nil, fmt.Errorf("preview list is corrupt: %w", err)
+	}
+	if i < 1 || i > len(records) {
+		return nil, fmt.Errorf("preview index %d is out of range", i)
+	}
+
+	r := records[i-1]
+	return &session.Session{
+		Name:    r.Name,
+		Dir:     r.Dir,
+		Exists:  r.Exists,
+		Aliases: r.Aliases,
+		Source:  r.Source,
+		Root:    r.Root,
+		Socket:  r.Socket,
+	}, nil
+}
+
+// socketFlags returns the flags that point a tm run by tm at the same tmux
+// server, already quoted for sh.
+func (a *App) socketFlags() []string {
+	socket := a.tmuxClient.Socket()
+	switch {
+	case socket.Path != "":
+		return []string{"--socket-path", shell.Quote(socket.Path)}
+	case socket.Name != "":
+		return []string{"--socket-name", shell.Quote(socket.Name)}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/griggsjared/tm/internal/session"
+	"github.com/griggsjared/tm/internal/tmux"
+)
+
+func TestApp_PreviewOptions(t *testing.T) {
+	sessions := []*session.Session{
+		{Name: "api", Dir: "/src/api", Exists: true, Source: session.SourcePreDefined},
+		{Name: "it's notes", Dir: "/home/me/notes", Source: session.SourceSmartDirectory, Root: "/home/me"},
+	}
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	app := New(&mockTmuxClient{socket: tmux.Socket{Name: "work"}}, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{CacheDir: cacheDir})
+	opts, cleanup := app.previewOptions(sessions)
+
+	if !strings.Contains(opts.Preview, " --config '/home/me/.config/tm/config.yaml' --socket-name 'work' preview --list '") || !strings.HasSuffix(opts.Preview, "' {1}") {
+		t.Fatalf("unexpected preview command %q", opts.Preview)
+	}
+	path := opts.Preview[strings.Index(opts.Preview, "--list '")+len("--list '") : len(opts.Preview)-len("' {1}")]
+	if filepath.Dir(path) != cacheDir {
+		t.Errorf("expected preview list in %s, got %s", cacheDir, path)
+	}
+
+	s, err := readPreviewCandidate(path, "2")
+	if err != nil {
+		t.Fatalf("readPreviewCandidate error: %v", err)
+	}
+	if s.Name != "it's notes" || s.Dir != "/home/me/notes" || s.Exists || s.Source != session.SourceSmartDirectory || s.Root != "/home/me" {
+		t.Errorf("unexpected candidate %+v", s)
+	}
+
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected preview list to be removed, got %v", err)
+	}
+}
+
+func TestReadPreviewCandidate(t *testing.T) {
+	path, err := writePreviewList("", []*session.Session{{Name: "api", Dir: "/src/api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(path) })
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.json")
+	os.WriteFile(corrupt, []byte("{"), 0600)
+
+	tests := []struct {
+		name    string
+		path    string
+		index   string
+		wantErr string
+	}{
+		{name: "first candidate", path: path, index: "1"},
+		{name: "missing list", path: "", index: "1", wantErr: "needs --list"},
+		{name: "not a number", path: path, index: "x", wantErr: "invalid preview index"},
+		{name: "out of range", path: path, index: "2", wantErr: "out of range"},
+		{name: "zero", path: path, index: "0", wantErr: "out of range"},
+		{name: "unreadable list", path: filepath.Join(t.TempDir(), "gone.json"), index: "1", wantErr: "inaccessible"},
+		{name: "corrupt list", path: corrupt, index: "1", wantErr: "corrupt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := readPreviewCandidate(tt.path, tt.index)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.Name != "api" || s.Dir != "/src/api" {
+				t.Errorf("unexpected candidate %+v", s)
+			}
+		})
+	}
+}
+
+func TestApp_Run_Preview(t *testing.T) {
+	path, err := writePreviewList("", []*session.Session{
+		{Name: "api", Dir: "/src/api", Exists: true},
+		{Name: "web", Dir: "/src/web"},
+		{Name: "scratch", Exists: false},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(path) })
+
+	tests := []struct {
+		name         string
+		index        string
+		tmux         *mockTmuxClient
+		wantExit     int
+		wantOutput   string
+		wantPreviews []string
+	}{
+		{
+			name:       "running session shows windows and pane",
+			index:      "1",
+			tmux:       &mockTmuxClient{available: true, windows: "0: editor (active) [1 panes]\n", pane: "$ make\n"},
+			wantOutput: "0: editor (active) [1 panes]\n\n$ make\n",
+		},
+		{
+			name:         "running session that tmux lost falls back to the project",
+			index:        "1",
+			tmux:         &mockTmuxClient{available: true, windowsError: os.ErrNotExist},
+			wantOutput:   "project /src/api\n",
+			wantPreviews: []string{"/src/api"},
+		},
+		{
+			name:         "project that is not running",
+			index:        "2",
+			tmux:         &mockTmuxClient{available: true},
+			wantOutput:   "project /src/web\n",
+			wantPreviews: []string{"/src/web"},
+		},
+		{
+			name:  "nothing to show without a directory",
+			index: "3",
+			tmux:  &mockTmuxClient{available: true},
+		},
+		{
+			name:     "bad index",
+			index:    "9",
+			tmux:     &mockTmuxClient{available: true},
+			wantExit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previewer := &mockPreviewer{}
+
+			oldStdout, oldStderr := os.Stdout, os.Stderr
+			r, w, _ := os.Pipe()
+			os.Stdout = w
+			_, errOut, _ := os.Pipe()
+			os.Stderr = errOut
+
+			app := New(tt.tmux, &mockFzfClient{}, &mockFzfClient{}, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, previewer, Options{})
+			got := app.Run(parseArgs(t, "preview", "--list", path, tt.index))
+
+			w.Close()
+			os.Stdout, os.Stderr = oldStdout, oldStderr
+			output, _ := io.ReadAll(r)
+
+			if got != tt.wantExit {
+				t.Errorf("Run(preview %s) = %d, want %d", tt.index, got, tt.wantExit)
+			}
+			if string(output) != tt.wantOutput {
+				t.Errorf("output = %q, want %q", output, tt.wantOutput)
+			}
+			if strings.Join(previewer.dirs, ",") != strings.Join(tt.wantPreviews, ",") {
+				t.Errorf("previewed %v, want %v", previewer.dirs, tt.wantPreviews)
+			}
+		})
+	}
+}
+
+func TestAppSelectSession_Preview(t *testing.T) {
+	fzfMock := &mockFzfClient{available: true, selectOk: true}
+	app := New(&mockTmuxClient{}, fzfMock, fzfMock, &mockSessionFinder{}, &mockHookRunner{}, &mockTrustStore{}, &mockHistoryStore{}, &mockConfigFile{}, &mockPreviewer{}, Options{})
+
+	if _, err := app.selectSession([]*session.Session{{Name: "api"}}, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	preview := fzfMock.providedOpts.Preview
+	if !strings.Contains(preview, " preview --list '") {
+		t.Fatalf("expected a preview command, got %q", preview)
+	}
+	path := preview[strings.Index(preview, "--list '")+len("--list '") : len(preview)-len("' {1}")]
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected preview list to be removed once the picker closes, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+// Package atomicfile replaces files so readers only ever see the old or the
+// new content.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Write replaces the file at path with content and gives it perm. It writes
+// to a temporary file next to path and renames it into place, so a crash
+// never leaves a truncated file and concurrent writers never share the
+// temporary file.
+func Write(path string, content []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Once renamed the temporary file is gone and this does nothing.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
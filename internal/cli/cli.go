@@ -0,0 +1,344 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Command is a parsed command line. Name is always set, defaulting to open
+// when the arguments do not start with a known command.
+type Command struct {
+	Name       string
+	Args       []string
+	Flags      map[string]string
+	Config     string
+	Debug      bool
+	SocketName string
+	SocketPath string
+}
+
+// Bool reports whether the boolean command flag name was given.
+func (c Command) Bool(name string) bool {
+	return c.Flags[name] == "true"
+}
+
+// String returns the value of the command flag name, or an empty string.
+func (c Command) String(name string) string {
+	return c.Flags[name]
+}
+
+// Flag describes a command line flag. A flag without a Value placeholder is
+// boolean. Aliases are older names the flag is still accepted under.
+type Flag struct {
+	Name    string
+	Aliases []string
+	Value   string
+	Usage   string
+}
+
+// Spec describes a command. MaxArgs limits the positional arguments, with a
+// negative value meaning no limit. Hidden commands are left out of the
+// general help because tm runs them itself.
+type Spec struct {
+	Name    string
+	Aliases []string
+	Args    string
+	Summary string
+	Flags   []Flag
+	MaxArgs int
+	Hidden  bool
+}
+
+var globalFlags = []Flag{
+	{Name: "config", Value: "PATH", Usage: "Use this config file instead of the default"},
+	{Name: "debug", Usage: "Print what tm is doing"},
+	{Name: "socket-name", Aliases: []string{"socket"}, Value: "NAME", Usage: "Talk to the tmux server on this socket name (tmux -L)"},
+	{Name: "socket-path", Value: "PATH", Usage: "Talk to the tmux server on this socket path (tmux -S)"},
+	{Name: "help", Usage: "Show help"},
+}
+
+var (
+	formatFlag  = Flag{Name: "format", Value: "FORMAT", Usage: "Print json, tsv or a Go template such as '{{.Name}}'"}
+	sourceFlag  = Flag{Name: "source", Value: "SOURCE", Usage: "Only list sessions from tmux, pre-defined or smart, comma-separated"}
+	serversFlag = Flag{Name: "servers", Value: "NAMES", Usage: "Also list sessions on these tmux socket names or paths, comma-separated"}
+)
+
+var commands = []Spec{
+	{
+		Name:    "open",
+		Args:    "[query]",
+		Summary: "Attach to or create a session. Without a query, or when it matches more than one session, pick one in the picker. This is the default command.",
+		MaxArgs: 1,
+	},
+	{
+		Name:    "popup",
+		Args:    "[query]",
+		Summary: "Open the picker in a tmux popup over the current pane, like open. Only works inside tmux. Bind it to a key with run-shell.",
+		MaxArgs: 1,
+	},
+	{
+		Name:    "ls",
+		Aliases: []string{"list"},
+		Summary: "List running sessions.",
+		Flags: []Flag{
+			{Name: "all", Usage: "Include pre-defined and smart directory sessions that are not running"},
+			formatFlag,
+			sourceFlag,
+			serversFlag,
+		},
+	},
+	{
+		Name:    "ls-all",
+		Aliases: []string{"list-all"},
+		Summary: "List all sessions, the same as ls --all.",
+		Flags:   []Flag{formatFlag, sourceFlag, serversFlag},
+	},
+	{
+		Name:    "kill",
+		Args:    "[query]",
+		Summary: "Kill running sessions. Without a query, or when it matches more than one session, pick several in the picker. tm asks for confirmation first.",
+		Flags: []Flag{
+			{Name: "all-but-current", Usage: "Kill every running session except the current one"},
+		},
+		MaxArgs: 1,
+	},
+	{
+		Name:    "rename",
+		Args:    "[old] [new]",
+		Summary: "Rename a running session. When the session is defined in the config file tm offers to rename it there too.",
+		MaxArgs: 2,
+	},
+	{
+		Name:    "history",
+		Args:    "[prune [name...]]",
+		Summary: "Show the attach history used for ranking. prune forgets the named sessions, or those whose directory is gone.",
+		MaxArgs: -1,
+	},
+	{
+		Name:    "status",
+		Summary: "Check tm and its dependencies.",
+	},
+	{
+		Name:    "config",
+		Args:    "check|show|convert|schema",
+		Summary: "Check, show or convert the config file. check reports unknown keys, invalid values, duplicate names and aliases, and missing directories, with their line numbers. show prints the config merged with its includes and host overlay, and where each value comes from. convert prints the config file in another format. schema prints a JSON Schema of the config file for editors.",
+		Flags: []Flag{
+			{Name: "to", Value: "FORMAT", Usage: "The format convert writes: yaml, toml or json"},
+		},
+		MaxArgs: 1,
+	},
+	{
+		Name:    "version",
+		Summary: "Show the tm version.",
+	},
+	{
+		Name:    "preview",
+		Args:    "<index>",
+		Summary: "Print the fzf preview of the candidate at index in a list written by the picker.",
+		Flags: []Flag{
+			{Name: "list", Value: "FILE", Usage: "The candidate list written by the picker"},
+		},
+		MaxArgs: 1,
+		Hidden:  true,
+	},
+	{
+		Name:    "help",
+		Args:    "[command]",
+		Summary: "Show help for tm or a command.",
+		MaxArgs: 1,
+	},
+}
+
+// Lookup returns the command called name, by its name or an alias.
+func Lookup(name string) (Spec, bool) {
+	for _, c := range commands {
+		if c.Name == name || slices.Contains(c.Aliases, name) {
+			return c, true
+		}
+	}
+	return Spec{}, false
+}
+
+// Parse parses the arguments after the program name. Global flags may appear
+// anywhere before --. Everything after -- is a positional argument, so
+// tm -- status opens a session called status.
+func Parse(args []string) (Command, error) {
+	cmd := Command{Flags: make(map[string]string)}
+	var spec Spec
+	var help bool
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			cmd.Args = append(cmd.Args, args[i+1:]...)
+			break
+		}
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if cmd.Name == "" {
+				if s, ok := Lookup(arg); ok {
+					spec = s
+					cmd.Name = s.Name
+					continue
+				}
+				spec, _ = Lookup("open")
+				cmd.Name = spec.Name
+			}
+			cmd.Args = append(cmd.Args, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if arg == "-h" {
+			name = "help"
+		}
+
+		flag, global := findFlag(globalFlags, name)
+		if !global {
+			var ok bool
+			flag, ok = findFlag(spec.Flags, name)
+			if !ok {
+				return Command{}, fmt.Errorf("unknown flag %s", arg)
+			}
+		}
+
+		if flag.Value == "" {
+			if !hasValue {
+				value = "true"
+			}
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Command{}, fmt.Errorf("invalid value %q for %s", value, "--"+flag.Name)
+			}
+			value = strconv.FormatBool(b)
+		} else if !hasValue {
+			if i+1 >= len(args) {
+				return Command{}, fmt.Errorf("%s needs a %s", "--"+flag.Name, flag.Value)
+			}
+			i++
+			value = args[i]
+		}
+
+		if !global {
+			cmd.Flags[flag.Name] = value
+			continue
+		}
+		switch flag.Name {
+		case "config":
+			cmd.Config = value
+		case "debug":
+			cmd.Debug = value == "true"
+		case "socket-name":
+			cmd.SocketName = value
+		case "socket-path":
+			cmd.SocketPath = value
+		case "help":
+			help = value == "true"
+		}
+	}
+
+	if cmd.Name == "" {
+		spec, _ = Lookup("open")
+		cmd.Name = spec.Name
+	}
+
+	if help {
+		if cmd.Name == "open" && len(cmd.Args) == 0 || cmd.Name == "help" {
+			cmd.Args = nil
+		} else {
+			cmd.Args = []string{cmd.Name}
+		}
+		cmd.Name = "help"
+		return cmd, nil
+	}
+
+	// ls-all is kept as a shorthand for ls --all.
+	if cmd.Name == "ls-all" {
+		cmd.Name = "ls"
+		cmd.Flags["all"] = "true"
+	}
+
+	if spec.MaxArgs >= 0 && len(cmd.Args) > spec.MaxArgs {
+		return Command{}, fmt.Errorf("too many arguments for %s", spec.Name)
+	}
+	return cmd, nil
+}
+
+func findFlag(flags []Flag, name string) (Flag, bool) {
+	for _, f := range flags {
+		if f.Name == name || slices.Contains(f.Aliases, name) {
+			return f, true
+		}
+	}
+	return Flag{}, false
+}
+
+// PrintHelp writes the general help, or the help of the command named in
+// args, to w.
+func PrintHelp(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		printUsage(w)
+		return nil
+	}
+
+	spec, ok := Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	usage := "tm " + spec.Name
+	if len(spec.Flags) > 0 {
+		usage += " [flags]"
+	}
+	if spec.Args != "" {
+		usage += " " + spec.Args
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", usage, spec.Summary)
+	if len(spec.Aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(spec.Aliases, ", "))
+	}
+	if len(spec.Flags) > 0 {
+		fmt.Fprintln(w, "\nFlags:")
+		printFlags(w, spec.Flags)
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  tm [flags] [query]")
+	fmt.Fprintln(w, "  tm [flags] <command> [args]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		if c.Hidden {
+			continue
+		}
+		summary, _, _ := strings.Cut(c.Summary, ". ")
+		fmt.Fprintf(w, "  %-10s %s\n", c.Name, strings.TrimSuffix(summary, "."))
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	printFlags(w, globalFlags)
+	fmt.Fprintln(w, "\nRun \"tm help <command>\" for more about a command.")
+	fmt.Fprintln(w, "Run \"tm -- <query>\" to open a session whose name is also a command.")
+}
+
+func printFlags(w io.Writer, flags []Flag) {
+	for _, f := range flags {
+		name := "--" + f.Name
+		if f.Name == "help" {
+			name = "-h, --help"
+		}
+		if f.Value != "" {
+			name += " " + f.Value
+		}
+		usage := f.Usage
+		for _, alias := range f.Aliases {
+			usage += ", also --" + alias
+		}
+		fmt.Fprintf(w, "  %-20s %s\n", name, usage)
+	}
+}
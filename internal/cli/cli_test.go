@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    Command
+		wantErr string
+	}{
+		{
+			name: "no arguments opens interactively",
+			args: nil,
+			want: Command{Name: "open"},
+		},
+		{
+			name: "query opens a session",
+			args: []string{"api"},
+			want: Command{Name: "open", Args: []string{"api"}},
+		},
+		{
+			name: "command",
+			args: []string{"status"},
+			want: Command{Name: "status"},
+		},
+		{
+			name: "popup with a query",
+			args: []string{"popup", "api"},
+			want: Command{Name: "popup", Args: []string{"api"}},
+		},
+		{
+			name: "config convert",
+			args: []string{"config", "convert", "--to", "toml"},
+			want: Command{Name: "config", Args: []string{"convert"}, Flags: map[string]string{"to": "toml"}},
+		},
+		{
+			name: "alias",
+			args: []string{"list"},
+			want: Command{Name: "ls"},
+		},
+		{
+			name: "ls-all is ls --all",
+			args: []string{"list-all"},
+			want: Command{Name: "ls", Flags: map[string]string{"all": "true"}},
+		},
+		{
+			name: "command flag",
+			args: []string{"ls", "--all"},
+			want: Command{Name: "ls", Flags: map[string]string{"all": "true"}},
+		},
+		{
+			name: "string command flag",
+			args: []string{"ls-all", "--format", "{{.Name}}"},
+			want: Command{Name: "ls", Flags: map[string]string{"all": "true", "format": "{{.Name}}"}},
+		},
+		{
+			name: "boolean flag with value",
+			args: []string{"kill", "--all-but-current=false", "api"},
+			want: Command{Name: "kill", Args: []string{"api"}, Flags: map[string]string{"all-but-current": "false"}},
+		},
+		{
+			name: "global flags before and after the command",
+			args: []string{"--config", "/tmp/tm.yaml", "kill", "--debug", "--socket=work", "api"},
+			want: Command{Name: "kill", Args: []string{"api"}, Config: "/tmp/tm.yaml", Debug: true, SocketName: "work"},
+		},
+		{
+			name: "socket name",
+			args: []string{"--socket-name", "work", "ls"},
+			want: Command{Name: "ls", SocketName: "work"},
+		},
+		{
+			name: "socket path",
+			args: []string{"--socket-path", "/tmp/tmux.sock", "ls", "--servers", "work,home"},
+			want: Command{Name: "ls", SocketPath: "/tmp/tmux.sock", Flags: map[string]string{"servers": "work,home"}},
+		},
+		{
+			name: "global flags with a query",
+			args: []string{"--debug", "api"},
+			want: Command{Name: "open", Args: []string{"api"}, Debug: true},
+		},
+		{
+			name: "double dash opens a session named like a command",
+			args: []string{"--", "status"},
+			want: Command{Name: "open", Args: []string{"status"}},
+		},
+		{
+			name: "double dash after a command",
+			args: []string{"kill", "--", "--weird"},
+			want: Command{Name: "kill", Args: []string{"--weird"}},
+		},
+		{
+			name: "command name as a later argument is positional",
+			args: []string{"rename", "status", "ls"},
+			want: Command{Name: "rename", Args: []string{"status", "ls"}},
+		},
+		{
+			name: "history takes any arguments",
+			args: []string{"history", "prune", "a", "b"},
+			want: Command{Name: "history", Args: []string{"prune", "a", "b"}},
+		},
+		{
+			name: "help command",
+			args: []string{"help", "kill"},
+			want: Command{Name: "help", Args: []string{"kill"}},
+		},
+		{
+			name: "help flag",
+			args: []string{"-h"},
+			want: Command{Name: "help"},
+		},
+		{
+			name: "help flag on a command",
+			args: []string{"rename", "--help"},
+			want: Command{Name: "help", Args: []string{"rename"}},
+		},
+		{
+			name: "help flag wins over argument errors",
+			args: []string{"rename", "a", "b", "c", "-h"},
+			want: Command{Name: "help", Args: []string{"rename"}},
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"kill", "--force"},
+			wantErr: "unknown flag --force",
+		},
+		{
+			name:    "flag of another command",
+			args:    []string{"ls", "--all-but-current"},
+			wantErr: "unknown flag",
+		},
+		{
+			name:    "missing flag value",
+			args:    []string{"--config"},
+			wantErr: "--config needs a PATH",
+		},
+		{
+			name:    "invalid boolean",
+			args:    []string{"--debug=maybe"},
+			wantErr: "invalid value",
+		},
+		{
+			name:    "too many queries",
+			args:    []string{"kill", "api", "web"},
+			wantErr: "too many arguments for kill",
+		},
+		{
+			name:    "too many rename arguments",
+			args:    []string{"rename", "a", "b", "c"},
+			wantErr: "too many arguments for rename",
+		},
+		{
+			name:    "too many open arguments",
+			args:    []string{"api", "web"},
+			wantErr: "too many arguments for open",
+		},
+		{
+			name:    "no arguments for status",
+			args:    []string{"status", "now"},
+			wantErr: "too many arguments for status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%v) unexpected error: %v", tt.args, err)
+			}
+
+			if got.Name != tt.want.Name || !slices.Equal(got.Args, tt.want.Args) {
+				t.Errorf("Parse(%v) = %s %v, want %s %v", tt.args, got.Name, got.Args, tt.want.Name, tt.want.Args)
+			}
+			if !maps.Equal(got.Flags, tt.want.Flags) {
+				t.Errorf("Parse(%v) flags = %v, want %v", tt.args, got.Flags, tt.want.Flags)
+			}
+			if got.Config != tt.want.Config || got.Debug != tt.want.Debug || got.SocketName != tt.want.SocketName || got.SocketPath != tt.want.SocketPath {
+				t.Errorf("Parse(%v) globals = %q %v %q %q, want %q %v %q %q", tt.args, got.Config, got.Debug, got.SocketName, got.SocketPath, tt.want.Config, tt.want.Debug, tt.want.SocketName, tt.want.SocketPath)
+			}
+		})
+	}
+}
+
+func TestCommand_Flags(t *testing.T) {
+	cmd := Command{Flags: map[string]string{"all": "true", "format": "json"}}
+	if !cmd.Bool("all") || cmd.Bool("missing") {
+		t.Errorf("unexpected Bool results")
+	}
+	if cmd.String("format") != "json" || cmd.String("missing") != "" {
+		t.Errorf("unexpected String results")
+	}
+}
+
+func TestPrintHelp(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "general help lists commands and flags",
+			want: []string{"Usage:", "kill", "rename", "--config PATH", "--socket-name NAME", "also --socket", "--socket-path PATH", "-h, --help", "tm -- <query>"},
+		},
+		{
+			name: "command help",
+			args: []string{"kill"},
+			want: []string{"Usage: tm kill [flags] [query]", "--all-but-current"},
+		},
+		{
+			name: "command help by alias",
+			args: []string{"list"},
+			want: []string{"Usage: tm ls [flags]", "Aliases: list", "--all"},
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"nope"},
+			wantErr: true,
+		},
+	}
+
+	t.Run("hidden commands are left out", func(t *testing.T) {
+		var buf bytes.Buffer
+		PrintHelp(&buf, nil)
+		if strings.Contains(buf.String(), "preview") {
+			t.Errorf("expected preview to be hidden:\n%s", buf.String())
+		}
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := PrintHelp(&buf, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PrintHelp(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(buf.String(), w) {
+					t.Errorf("PrintHelp(%v) output missing %q:\n%s", tt.args, w, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Problem is a mistake in the config file, or a file it includes, that tm
+// would otherwise ignore or only report without saying where.
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	where := p.File
+	switch {
+	case p.Line != 0 && where != "":
+		where = fmt.Sprintf("%s:%d", where, p.Line)
+	case p.Line != 0:
+		where = fmt.Sprintf("line %d", p.Line)
+	}
+	if where == "" {
+		return p.Message
+	}
+	return where + ": " + p.Message
+}
+
+// Check reads the config file, the files it includes and its host overlay
+// strictly and reports unknown keys, invalid values, duplicate session names
+// and aliases, aliases that shadow a session name, and directories that are
+// missing or do not exist. An error means a file could not be read or parsed
+// at all.
+func (f *File) Check() ([]Problem, error) {
+	envConfig, err := loadConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return check(f.path, resolveProfile(envConfig.Profile))
+}
+
+// check checks the config file at path merged with its includes and the
+// overlay for profile. Each file is checked on its own, while names and
+// aliases are checked across the sessions the files define together.
+// Problems in TOML files have no line, as the TOML parser does not keep one.
+func check(path, profile string) ([]Problem, error) {
+	layers, err := readLayers(path, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	order := make(map[string]int, len(layers))
+	for i, l := range layers {
+		order[l.path] = i
+		found, err := checkLayer(l)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, found...)
+	}
+	problems = append(problems, checkSessions(sessionDefinitions(layers))...)
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		if c := order[a.File] - order[b.File]; c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	return problems, nil
+}
+
+// checkLayer reports the problems of one file that do not depend on the
+// others.
+func checkLayer(l layer) ([]Problem, error) {
+	var c fileConfig
+	var problems []Problem
+	if err := l.root.Decode(&c); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("invalid config file %s: %w", l.path, err)
+		}
+		for _, msg := range typeErr.Errors {
+			problems = append(problems, typeProblem(msg))
+		}
+	}
+
+	problems = append(problems, unknownKeys(l.root, reflect.TypeOf(c), "")...)
+	problems = append(problems, checkSettings(l.root, c)...)
+	problems = append(problems, checkSmartDirectories(mappingValue(l.root, "smart_directories"))...)
+	for i := range problems {
+		problems[i].File = l.path
+	}
+	return problems, nil
+}
+
+var typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// typeProblem turns one of the messages of a yaml.TypeError, which start
+// with the line they are about, into a Problem.
+func typeProblem(msg string) Problem {
+	m := typeErrorLine.FindStringSubmatch(msg)
+	if m == nil {
+		return Problem{Message: msg}
+	}
+	line, _ := strconv.Atoi(m[1])
+	return Problem{Line: line, Message: m[2]}
+}
+
+// unknownKeys walks node alongside the type it decodes into and reports
+// every mapping key that type has no field for. Values written in a short
+// form, such as a smart directory given as a plain path, are skipped.
+func unknownKeys(node *yaml.Node, t reflect.Type, path string) []Problem {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var problems []Problem
+	switch t.Kind() {
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for _, item := range node.Content {
+			problems = append(problems, unknownKeys(item, t.Elem(), path)...)
+		}
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			name := key.Value
+			if path != "" {
+				name = path + "." + key.Value
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				problems = append(problems, Problem{Line: key.Line, Message: fmt.Sprintf("unknown key %q", name)})
+				continue
+			}
+			problems = append(problems, unknownKeys(value, field, name)...)
+		}
+	}
+	return problems
+}
+
+// yamlFields maps the yaml keys of a struct, including those of inlined
+// structs, to the types of their fields.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if opts == "inline" {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// checkSettings reports the top level values Load would reject.
+func checkSettings(root *yaml.Node, c fileConfig) []Problem {
+	var problems []Problem
+	if _, err := parsePicker(c.Picker); err != nil {
+		problems = append(problems, Problem{Line: nodeLine(root, "picker"), Message: err.Error()})
+	}
+	if _, err := parseMatchStrategy(c.Match.Strategy); err != nil {
+		problems = append(problems, Problem{Line: nodeLine(root, "match", "strategy"), Message: err.Error()})
+	}
+	if _, err := c.Popup.toPopup(); err != nil {
+		problems = append(problems, Problem{Line: nodeLine(root, "popup"), Message: err.Error()})
+	}
+	return problems
+}
+
+// definition is a session as one of the files defines it.
+type definition struct {
+	path string
+	node *yaml.Node
+}
+
+// sessionDefinitions returns the sessions of layers in the order they are
+// merged, leaving out those a later file overrides. Sessions repeated within
+// one file are all kept, as that is a mistake rather than an override.
+func sessionDefinitions(layers []layer) []definition {
+	var defs []definition
+	for _, l := range layers {
+		sessions := mappingValue(l.root, "sessions")
+		if sessions == nil || sessions.Kind != yaml.SequenceNode {
+			continue
+		}
+		ids := make(map[string]bool)
+		for _, s := range sessions.Content {
+			if id := sessionID(s); id != "" {
+				ids[id] = true
+			}
+		}
+		defs = slices.DeleteFunc(defs, func(d definition) bool {
+			return ids[sessionID(d.node)]
+		})
+		for _, s := range sessions.Content {
+			defs = append(defs, definition{path: l.path, node: s})
+		}
+	}
+	return defs
+}
+
+// checkSessions reports sessions without a usable dir, and names and
+// aliases that are used more than once. A session on another tmux server
+// may reuse a name, but aliases are looked up across every server.
+func checkSessions(defs []definition) []Problem {
+	type named struct {
+		socket string
+		at     source
+	}
+	var problems []Problem
+	names := make(map[string][]named)
+	for _, d := range defs {
+		name := scalarValue(d.node, "name")
+		if name == nil {
+			continue
+		}
+		socket := ""
+		if n := scalarValue(d.node, "socket"); n != nil {
+			socket = n.Value
+		}
+		for _, n := range names[name.Value] {
+			if n.socket == socket {
+				problems = append(problems, Problem{File: d.path, Line: name.Line, Message: fmt.Sprintf("session name %q is already used%s", name.Value, definedAt(d.path, n.at))})
+				break
+			}
+		}
+		names[name.Value] = append(names[name.Value], named{socket: socket, at: source{path: d.path, line: name.Line}})
+	}
+
+	aliases := make(map[string]source)
+	for _, d := range defs {
+		label := "session"
+		if name := scalarValue(d.node, "name"); name != nil {
+			label = fmt.Sprintf("session %q", name.Value)
+		}
+		for _, p := range checkDir(d.node, label) {
+			p.File = d.path
+			problems = append(problems, p)
+		}
+
+		list := mappingValue(d.node, "aliases")
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, alias := range list.Content {
+			if alias.Kind != yaml.ScalarNode {
+				continue
+			}
+			if at, ok := aliases[alias.Value]; ok {
+				problems = append(problems, Problem{File: d.path, Line: alias.Line, Message: fmt.Sprintf("alias %q is already used%s", alias.Value, definedAt(d.path, at))})
+				continue
+			}
+			aliases[alias.Value] = source{path: d.path, line: alias.Line}
+			if defs, ok := names[alias.Value]; ok {
+				problems = append(problems, Problem{File: d.path, Line: alias.Line, Message: fmt.Sprintf("alias %q shadows the session named%s", alias.Value, definedAt(d.path, defs[0].at))})
+			}
+		}
+	}
+	return problems
+}
+
+// definedAt refers to an earlier definition from a problem in the file at
+// path: by its line within the same file, and by file and line otherwise.
+func definedAt(path string, at source) string {
+	if at.path != path {
+		return " in " + at.String()
+	}
+	return onLine(at.line)
+}
+
+// onLine refers to an earlier definition by its line, when it has one.
+func onLine(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" on line %d", line)
+}
+
+// checkSmartDirectories reports smart directories without a usable dir or
+// with a depth below 1.
+func checkSmartDirectories(dirs *yaml.Node) []Problem {
+	if dirs == nil || dirs.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var problems []Problem
+	for _, d := range dirs.Content {
+		if d.Kind == yaml.ScalarNode {
+			problems = append(problems, checkDirValue(d, "smart directory")...)
+			continue
+		}
+		problems = append(problems, checkDir(d, "smart directory")...)
+		if depth := scalarValue(d, "depth"); depth != nil {
+			if n, err := strconv.Atoi(depth.Value); err == nil && n < 1 {
+				problems = append(problems, Problem{Line: depth.Line, Message: fmt.Sprintf("smart directory: invalid depth %d, want 1 or more", n)})
+			}
+		}
+	}
+	return problems
+}
+
+// checkDir reports a mapping whose dir key is missing, empty or names a
+// directory that does not exist.
+func checkDir(node *yaml.Node, label string) []Problem {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	dir := scalarValue(node, "dir")
+	if dir == nil {
+		return []Problem{{Line: node.Line, Message: label + ": dir is missing"}}
+	}
+	return checkDirValue(dir, label)
+}
+
+func checkDirValue(dir *yaml.Node, label string) []Problem {
+	if dir.Value == "" {
+		return []Problem{{Line: dir.Line, Message: label + ": dir is empty"}}
+	}
+	path := dir.Value
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return []Problem{{Line: dir.Line, Message: fmt.Sprintf("%s: dir %q does not exist", label, dir.Value)}}
+	}
+	return nil
+}
+
+// scalarValue returns the scalar value of key in a mapping, or nil.
+func scalarValue(node *yaml.Node, key string) *yaml.Node {
+	if n := mappingValue(node, key); n != nil && n.Kind == yaml.ScalarNode {
+		return n
+	}
+	return nil
+}
+
+// nodeLine returns the line of the value at the path of keys, or of the
+// deepest key that exists on the way.
+func nodeLine(node *yaml.Node, keys ...string) int {
+	line := node.Line
+	for _, key := range keys {
+		node = mappingValue(node, key)
+		if node == nil {
+			break
+		}
+		line = node.Line
+	}
+	return line
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFile_Check(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "valid config",
+			content: "picker: fzf\nsessions:\n  - name: api\n    dir: " + dir + "\n    aliases: [a]\n    on_create: [make]\nsmart_directories:\n  - " + dir + "\n  - dir: " + dir + "\n    depth: 2\n",
+		},
+		{
+			name:    "empty file",
+			content: "",
+		},
+		{
+			name:    "unknown keys",
+			content: "smart_directory:\n  - " + dir + "\nsessions:\n  - name: api\n    dir: " + dir + "\n    alias: [a]\n    windows:\n      - panes:\n          - comand: ls\nsmart_directories:\n  - dir: " + dir + "\n    depht: 2\n",
+			want: []string{
+				`config.yaml:1: unknown key "smart_directory"`,
+				`config.yaml:6: unknown key "sessions.alias"`,
+				`config.yaml:9: unknown key "sessions.windows.panes.comand"`,
+				`config.yaml:12: unknown key "smart_directories.depht"`,
+			},
+		},
+		{
+			name:    "duplicate names and aliases",
+			content: "sessions:\n  - name: api\n    dir: " + dir + "\n    aliases: [a, web]\n  - name: api\n    dir: " + dir + "\n    aliases: [a]\n  - name: web\n    dir: " + dir + "\n",
+			want: []string{
+				`config.yaml:4: alias "web" shadows the session named on line 8`,
+				`config.yaml:5: session name "api" is already used on line 2`,
+				`config.yaml:7: alias "a" is already used on line 4`,
+			},
+		},
+		{
+			name:    "same name on another server",
+			content: "sessions:\n  - name: api\n    dir: " + dir + "\n  - name: api\n    dir: " + dir + "\n    socket: work\n",
+		},
+		{
+			name:    "missing and empty dirs",
+			content: "sessions:\n  - name: api\n  - name: web\n    dir: \"\"\n  - name: docs\n    dir: " + missing + "\nsmart_directories:\n  - " + missing + "\n",
+			want: []string{
+				`config.yaml:2: session "api": dir is missing`,
+				`config.yaml:4: session "web": dir is empty`,
+				`config.yaml:6: session "docs": dir "` + missing + `" does not exist`,
+				`config.yaml:8: smart directory: dir "` + missing + `" does not exist`,
+			},
+		},
+		{
+			name:    "depth below 1",
+			content: "smart_directories:\n  - dir: " + dir + "\n    depth: 0\n  - dir: " + dir + "\n    depth: -2\n  - dir: " + dir + "\n    depth: 1\n",
+			want: []string{
+				`config.yaml:3: smart directory: invalid depth 0, want 1 or more`,
+				`config.yaml:5: smart directory: invalid depth -2, want 1 or more`,
+			},
+		},
+		{
+			name:    "invalid values",
+			content: "picker: skim\nmatch:\n  strategy: exact\npopup:\n  width: huge\nsessions:\n  - name: api\n    dir: " + dir + "\n    windows: nope\n",
+			want: []string{
+				`config.yaml:1: invalid picker "skim", want auto, fzf or builtin`,
+				`config.yaml:3: invalid match strategy "exact", want prefix or ranked`,
+				`config.yaml:5: invalid popup width "huge", want a number of columns or a percentage`,
+				"config.yaml:9: cannot unmarshal !!str `nope` into []config.windowConfig",
+			},
+		},
+		{
+			name:    "not a mapping",
+			content: "- api\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			content: "sessions:\n  - name: api\n   dir: x\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate keys",
+			content: "picker: fzf\npicker: builtin\n",
+			want:    []string{`config.yaml:2: mapping key "picker" already defined at line 1`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			problems, err := NewFile(path).Check()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "line") {
+					t.Fatalf("expected an error with a line number, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, p := range problems {
+				p.File = filepath.Base(p.File)
+				got = append(got, p.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check() =\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := NewFile(missing).Check(); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestFile_Check_Includes(t *testing.T) {
+	t.Setenv("TM_PROFILE", "laptop")
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml":        "include: [work.yaml]\nsessions:\n  - name: api\n    dir: /\n    aliases: [a]\n",
+		"work.yaml":          "picker: skim\nsessions:\n  - name: web\n    dir: /\n    aliases: [a, api]\n  - name: web\n    dir: /\n",
+		"config.laptop.yaml": "sessions:\n  - name: api\n    dir: /\n    alias: [b]\n",
+	})
+
+	problems, err := NewFile(filepath.Join(dir, "config.yaml")).Check()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, strings.TrimPrefix(p.String(), dir+string(filepath.Separator)))
+	}
+	// The overlay replaces api, so its alias a is no longer taken, but web
+	// now shadows api with an alias.
+	want := []string{
+		`work.yaml:1: invalid picker "skim", want auto, fzf or builtin`,
+		`work.yaml:5: alias "api" shadows the session named in ` + filepath.Join(dir, "config.laptop.yaml") + `:2`,
+		`work.yaml:6: session name "web" is already used on line 3`,
+		`config.laptop.yaml:4: unknown key "sessions.alias"`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Check() =\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
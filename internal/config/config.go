@@ -6,6 +6,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/sethvargo/go-envconfig"
 	yaml "gopkg.in/yaml.v3"
@@ -16,22 +19,74 @@ import (
 type Config struct {
 	Debug              bool
 	TmuxPath           string
+	TmuxSocket         string
 	FzfPath            string
+	Picker             string
+	Match              Match
+	Popup              Popup
+	ConfigPath         string
+	StateDir           string
+	CacheDir           string
 	PreDefinedSessions []session.PreDefinedSession
 	SmartDirectories   []session.SmartDirectory
+	// Warnings lists what tm config check finds in the config file and the
+	// files it includes. It is only filled in when TM_DEBUG is set.
+	Warnings []Problem
 }
 
-func New(debug bool, tmuxPath, fzfPath string, preDefinedSessions []session.PreDefinedSession, smartDirectories []session.SmartDirectory) *Config {
+// Pickers tm can use to choose a session. auto uses fzf when it is installed
+// and the built-in picker otherwise.
+const (
+	PickerAuto    = "auto"
+	PickerFzf     = "fzf"
+	PickerBuiltin = "builtin"
+)
+
+// Strategies for resolving a query without the picker. prefix matches the
+// start of names and aliases. ranked also matches words inside them,
+// substrings and subsequences.
+const (
+	MatchPrefix = "prefix"
+	MatchRanked = "ranked"
+)
+
+// Match is how tm resolves a query without the picker. Dirs also matches the
+// session directory.
+type Match struct {
+	Strategy string
+	Dirs     bool
+}
+
+// Popup is the size and border of the tmux popup tm popup opens. Always opens
+// the picker in a popup whenever tm runs inside tmux without a query.
+type Popup struct {
+	Width  string
+	Height string
+	Border string
+	Always bool
+}
+
+func New(debug bool, tmuxPath, tmuxSocket, fzfPath, picker string, match Match, popup Popup, configPath, stateDir, cacheDir string, preDefinedSessions []session.PreDefinedSession, smartDirectories []session.SmartDirectory) *Config {
 	return &Config{
 		Debug:              debug,
 		TmuxPath:           tmuxPath,
+		TmuxSocket:         tmuxSocket,
 		FzfPath:            fzfPath,
+		Picker:             picker,
+		Match:              match,
+		Popup:              popup,
+		ConfigPath:         configPath,
+		StateDir:           stateDir,
+		CacheDir:           cacheDir,
 		PreDefinedSessions: preDefinedSessions,
 		SmartDirectories:   smartDirectories,
 	}
 }
 
-func Load() (*Config, error) {
+// Load reads the config from the environment and the config file. A non-empty
+// configPath takes precedence over TM_CONFIG_PATH, which takes precedence
+// over the config file found by defaultConfigPath.
+func Load(configPath string) (*Config, error) {
 	envConfig, err := loadConfigFromEnv()
 	if err != nil {
 		return nil, err
@@ -42,12 +97,43 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	configPath := envConfig.ConfigPath
+	if configPath == "" {
+		configPath = envConfig.ConfigPath
+	}
 	if configPath == "" {
 		configPath = defaultConfigPath
 	}
 
-	fileConfig, err := loadConfigFromConfigFile(configPath, defaultConfigPath)
+	fileConfig, err := loadConfigFromConfigFile(configPath, defaultConfigPath, resolveProfile(envConfig.Profile), !envConfig.NoCreateConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	stateDir, err := defaultStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheDir, err := defaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	picker := envConfig.Picker
+	if picker == "" {
+		picker = fileConfig.Picker
+	}
+	picker, err = parsePicker(picker)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, err := parseMatchStrategy(fileConfig.Match.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	popup, err := fileConfig.Popup.toPopup()
 	if err != nil {
 		return nil, err
 	}
@@ -58,23 +144,69 @@ func Load() (*Config, error) {
 			Dir:     pd.Dir,
 			Name:    pd.Name,
 			Aliases: pd.Aliases,
+			Socket:  pd.Socket,
+			Windows: toWindows(pd.Windows),
+			Hooks:   pd.Hooks.toHooks(),
+			Env:     pd.Env,
 		}
 	}
 
 	smartDirectories := make([]session.SmartDirectory, len(fileConfig.SmartDirectories))
 	for i, sd := range fileConfig.SmartDirectories {
 		smartDirectories[i] = session.SmartDirectory{
-			Dir: sd,
+			Dir:          sd.Dir,
+			Depth:        sd.Depth,
+			Markers:      sd.Markers,
+			Ignore:       sd.Ignore,
+			Include:      sd.Include,
+			ShowHidden:   sd.ShowHidden,
+			Prefix:       sd.Prefix,
+			NameTemplate: sd.NameTemplate,
+			Hooks:        sd.Hooks.toHooks(),
+			Env:          sd.Env,
 		}
 	}
 
-	return New(
+	cfg := New(
 		envConfig.Debug,
 		resolveBinaryPath(envConfig.TmuxPath, "tmux"),
+		envConfig.TmuxSocket,
 		resolveBinaryPath(envConfig.FzfPath, "fzf"),
+		picker,
+		Match{Strategy: strategy, Dirs: fileConfig.Match.Dirs},
+		popup,
+		configPath,
+		stateDir,
+		cacheDir,
 		preDefinedSessions,
 		smartDirectories,
-	), nil
+	)
+	if envConfig.Debug {
+		if cfg.Warnings, err = NewFile(configPath).Check(); err != nil {
+			cfg.Warnings = []Problem{{Message: err.Error()}}
+		}
+	}
+	return cfg, nil
+}
+
+func parsePicker(value string) (string, error) {
+	switch value {
+	case "":
+		return PickerAuto, nil
+	case PickerAuto, PickerFzf, PickerBuiltin:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid picker %q, want auto, fzf or builtin", value)
+}
+
+func parseMatchStrategy(value string) (string, error) {
+	switch value {
+	case "":
+		return MatchRanked, nil
+	case MatchPrefix, MatchRanked:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid match strategy %q, want prefix or ranked", value)
 }
 
 func resolveBinaryPath(envPath, binaryName string) string {
@@ -93,8 +225,14 @@ func resolveBinaryPath(envPath, binaryName string) string {
 type envConfig struct {
 	Debug      bool   `env:"TM_DEBUG"`
 	TmuxPath   string `env:"TM_TMUX_PATH"`
+	TmuxSocket string `env:"TM_TMUX_SOCKET"`
 	FzfPath    string `env:"TM_FZF_PATH"`
+	Picker     string `env:"TM_PICKER"`
 	ConfigPath string `env:"TM_CONFIG_PATH"`
+	Profile    string `env:"TM_PROFILE"`
+	// NoCreateConfig stops tm from creating a missing config file, for
+	// read-only home directories. tm then runs without a config file.
+	NoCreateConfig bool `env:"TM_NO_CREATE_CONFIG"`
 }
 
 func loadConfigFromEnv() (*envConfig, error) {
@@ -108,28 +246,197 @@ func loadConfigFromEnv() (*envConfig, error) {
 }
 
 type fileConfig struct {
-	PreDefinedSessions []struct {
-		Dir     string   `yaml:"dir"`
-		Name    string   `yaml:"name"`
-		Aliases []string `yaml:"aliases"`
-	} `yaml:"sessions"`
-	SmartDirectories []string `yaml:"smart_directories"`
+	Include            []string               `yaml:"include"`
+	Picker             string                 `yaml:"picker"`
+	Match              matchConfig            `yaml:"match"`
+	Popup              popupConfig            `yaml:"popup"`
+	PreDefinedSessions []sessionConfig        `yaml:"sessions"`
+	SmartDirectories   []smartDirectoryConfig `yaml:"smart_directories"`
 }
 
-func loadConfigFromConfigFile(path, defaultPath string) (*fileConfig, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
+type matchConfig struct {
+	Strategy string `yaml:"strategy"`
+	Dirs     bool   `yaml:"dirs"`
+}
+
+type popupConfig struct {
+	Width  string `yaml:"width"`
+	Height string `yaml:"height"`
+	Border string `yaml:"border"`
+	Always bool   `yaml:"always"`
+}
+
+// popupBorders are the border styles tmux accepts for display-popup -b.
+var popupBorders = []string{"single", "rounded", "double", "heavy", "simple", "padded", "none"}
+
+var popupSize = regexp.MustCompile(`^[1-9][0-9]*%?$`)
+
+// toPopup checks the popup settings and fills in the default size, 80% of
+// the terminal each way.
+func (c popupConfig) toPopup() (Popup, error) {
+	p := Popup{Width: c.Width, Height: c.Height, Border: c.Border, Always: c.Always}
+	if p.Width == "" {
+		p.Width = "80%"
+	}
+	if p.Height == "" {
+		p.Height = "80%"
+	}
+	if !popupSize.MatchString(p.Width) {
+		return Popup{}, fmt.Errorf("invalid popup width %q, want a number of columns or a percentage", p.Width)
+	}
+	if !popupSize.MatchString(p.Height) {
+		return Popup{}, fmt.Errorf("invalid popup height %q, want a number of lines or a percentage", p.Height)
+	}
+	if p.Border != "" && !slices.Contains(popupBorders, p.Border) {
+		return Popup{}, fmt.Errorf("invalid popup border %q, want one of %s", p.Border, strings.Join(popupBorders, ", "))
+	}
+	return p, nil
+}
+
+type sessionConfig struct {
+	Dir     string            `yaml:"dir"`
+	Name    string            `yaml:"name"`
+	Aliases []string          `yaml:"aliases"`
+	Socket  string            `yaml:"socket"`
+	Windows []windowConfig    `yaml:"windows"`
+	Env     map[string]string `yaml:"env"`
+	Hooks   hooksConfig       `yaml:",inline"`
+}
+
+// smartDirectoryConfig accepts either a plain path or a mapping with a dir
+// key, so existing configs that list bare paths keep working.
+type smartDirectoryConfig struct {
+	Dir          string            `yaml:"dir"`
+	Depth        int               `yaml:"depth"`
+	Markers      []string          `yaml:"markers"`
+	Ignore       []string          `yaml:"ignore"`
+	Include      []string          `yaml:"include"`
+	ShowHidden   bool              `yaml:"show_hidden"`
+	Prefix       string            `yaml:"prefix"`
+	NameTemplate string            `yaml:"name_template"`
+	Env          map[string]string `yaml:"env"`
+	Hooks        hooksConfig       `yaml:",inline"`
+}
+
+func (c *smartDirectoryConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Dir = value.Value
+		return nil
+	}
+	type plain smartDirectoryConfig
+	return value.Decode((*plain)(c))
+}
+
+type hooksConfig struct {
+	OnCreate []hookConfig `yaml:"on_create"`
+	OnAttach []hookConfig `yaml:"on_attach"`
+	OnDetach []hookConfig `yaml:"on_detach"`
+	OnKill   []hookConfig `yaml:"on_kill"`
+}
+
+func (c hooksConfig) toHooks() session.Hooks {
+	return session.Hooks{
+		OnCreate: toHookList(c.OnCreate),
+		OnAttach: toHookList(c.OnAttach),
+		OnDetach: toHookList(c.OnDetach),
+		OnKill:   toHookList(c.OnKill),
+	}
+}
+
+// hookConfig accepts either a plain command or a mapping with command and
+// abort keys.
+type hookConfig struct {
+	Command string `yaml:"command"`
+	Abort   bool   `yaml:"abort"`
+}
+
+func (c *hookConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Command = value.Value
+		return nil
+	}
+	type plain hookConfig
+	return value.Decode((*plain)(c))
+}
+
+func toHookList(hooks []hookConfig) []session.Hook {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	result := make([]session.Hook, len(hooks))
+	for i, h := range hooks {
+		result[i] = session.Hook{Command: h.Command, Abort: h.Abort}
+	}
+	return result
+}
+
+type windowConfig struct {
+	Name    string       `yaml:"name"`
+	Dir     string       `yaml:"dir"`
+	Layout  string       `yaml:"layout"`
+	Command string       `yaml:"command"`
+	Enter   *bool        `yaml:"enter"`
+	Panes   []paneConfig `yaml:"panes"`
+}
+
+type paneConfig struct {
+	Dir     string `yaml:"dir"`
+	Command string `yaml:"command"`
+	Enter   *bool  `yaml:"enter"`
+}
+
+func toWindows(windows []windowConfig) []session.Window {
+	if len(windows) == 0 {
+		return nil
+	}
+
+	result := make([]session.Window, len(windows))
+	for i, w := range windows {
+		result[i] = session.Window{
+			Name:    w.Name,
+			Dir:     w.Dir,
+			Layout:  w.Layout,
+			Command: w.Command,
+			NoEnter: w.Enter != nil && !*w.Enter,
+		}
+		for _, p := range w.Panes {
+			result[i].Panes = append(result[i].Panes, session.Pane{
+				Dir:     p.Dir,
+				Command: p.Command,
+				NoEnter: p.Enter != nil && !*p.Enter,
+			})
+		}
+	}
+	return result
+}
+
+// loadConfigFromConfigFile reads the config file at path merged with the
+// files it includes and its overlay for profile. A missing default config
+// file is created empty when create is set, and read as empty otherwise.
+func loadConfigFromConfigFile(path, defaultPath, profile string, create bool) (*fileConfig, error) {
+	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
 			if path != defaultPath {
 				return nil, fmt.Errorf("config file does not exist: %w", err)
 			}
+			if !create {
+				return &fileConfig{}, nil
+			}
 			return createDefaultConfigFile(defaultPath)
 		}
 		return nil, fmt.Errorf("config file is inaccessible: %w", err)
 	}
 
+	layers, err := readLayers(path, profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeLayers(layers); err != nil {
+		return nil, err
+	}
 	var c fileConfig
-	if err := yaml.Unmarshal(content, &c); err != nil {
+	if err := mergeLayers(layers).root.Decode(&c); err != nil {
 		return nil, err
 	}
 	return &c, nil
@@ -145,10 +452,86 @@ func createDefaultConfigFile(path string) (*fileConfig, error) {
 	return &fileConfig{}, nil
 }
 
+// Path returns the config file Load reads: configPath when it is not empty,
+// then TM_CONFIG_PATH, then the default path.
+func Path(configPath string) (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	envConfig, err := loadConfigFromEnv()
+	if err != nil {
+		return "", err
+	}
+	if envConfig.ConfigPath != "" {
+		return envConfig.ConfigPath, nil
+	}
+	return defaultConfigPath()
+}
+
+// configExtensions are the config file formats tm looks for, in order.
+var configExtensions = []string{".yaml", ".yml", ".toml", ".json"}
+
+// defaultConfigPath finds the config file in $XDG_CONFIG_HOME/tm, trying
+// each of configExtensions, and then at the legacy ~/.config/tm/config.yaml.
+// When there is none it returns $XDG_CONFIG_HOME/tm/config.yaml, where a
+// new config file is created. XDG_CONFIG_HOME defaults to ~/.config.
 func defaultConfigPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("unable to determine home directory: %w", err)
 	}
-	return filepath.Join(homeDir, ".config", "tm", "config.yaml"), nil
+	dir := filepath.Join(homeDir, ".config", "tm")
+	legacy := filepath.Join(dir, "config.yaml")
+	if xdg := xdgDir("XDG_CONFIG_HOME"); xdg != "" {
+		dir = filepath.Join(xdg, "tm")
+	}
+	for _, ext := range configExtensions {
+		if path := filepath.Join(dir, "config"+ext); fileExists(path) {
+			return path, nil
+		}
+	}
+	if fileExists(legacy) {
+		return legacy, nil
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// xdgDir returns the directory in the XDG base directory variable name. The
+// spec has relative paths ignored, so one is treated like an unset variable.
+func xdgDir(name string) string {
+	if dir := os.Getenv(name); filepath.IsAbs(dir) {
+		return dir
+	}
+	return ""
+}
+
+// defaultStateDir returns the directory for data tm keeps between runs, such
+// as trusted project files.
+func defaultStateDir() (string, error) {
+	if dir := xdgDir("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "tm"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".local", "state", "tm"), nil
+}
+
+// defaultCacheDir returns the directory for files tm can always recreate,
+// such as the candidate lists the fzf preview reads.
+func defaultCacheDir() (string, error) {
+	if dir := xdgDir("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "tm"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".cache", "tm"), nil
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -18,7 +19,7 @@ func TestNew(t *testing.T) {
 		{Dir: "~/projects"},
 	}
 
-	cfg := New(true, "/usr/bin/tmux", "/usr/bin/fzf", pds, sd)
+	cfg := New(true, "/usr/bin/tmux", "work", "/usr/bin/fzf", "builtin", Match{Strategy: "prefix", Dirs: true}, Popup{Width: "60%", Always: true}, "/home/me/.config/tm/config.yaml", "/home/me/.local/state/tm", "/home/me/.cache/tm", pds, sd)
 
 	if !cfg.Debug {
 		t.Error("expected Debug to be true")
@@ -26,9 +27,30 @@ func TestNew(t *testing.T) {
 	if cfg.TmuxPath != "/usr/bin/tmux" {
 		t.Errorf("expected TmuxPath /usr/bin/tmux, got %s", cfg.TmuxPath)
 	}
+	if cfg.TmuxSocket != "work" {
+		t.Errorf("expected TmuxSocket work, got %s", cfg.TmuxSocket)
+	}
 	if cfg.FzfPath != "/usr/bin/fzf" {
 		t.Errorf("expected FzfPath /usr/bin/fzf, got %s", cfg.FzfPath)
 	}
+	if cfg.Picker != "builtin" {
+		t.Errorf("expected Picker builtin, got %s", cfg.Picker)
+	}
+	if cfg.Match != (Match{Strategy: "prefix", Dirs: true}) {
+		t.Errorf("expected Match prefix with dirs, got %+v", cfg.Match)
+	}
+	if cfg.Popup != (Popup{Width: "60%", Always: true}) {
+		t.Errorf("expected Popup, got %+v", cfg.Popup)
+	}
+	if cfg.ConfigPath != "/home/me/.config/tm/config.yaml" {
+		t.Errorf("expected ConfigPath /home/me/.config/tm/config.yaml, got %s", cfg.ConfigPath)
+	}
+	if cfg.StateDir != "/home/me/.local/state/tm" {
+		t.Errorf("expected StateDir /home/me/.local/state/tm, got %s", cfg.StateDir)
+	}
+	if cfg.CacheDir != "/home/me/.cache/tm" {
+		t.Errorf("expected CacheDir /home/me/.cache/tm, got %s", cfg.CacheDir)
+	}
 	if len(cfg.PreDefinedSessions) != 1 || cfg.PreDefinedSessions[0].Name != "test" {
 		t.Errorf("expected PreDefinedSessions, got %v", cfg.PreDefinedSessions)
 	}
@@ -38,20 +60,58 @@ func TestNew(t *testing.T) {
 }
 
 func TestDefaultConfigPath(t *testing.T) {
-	t.Run("success", func(t *testing.T) {
-		home := os.Getenv("HOME")
-		if home == "" {
-			t.Skip("HOME not set, skipping test")
-		}
+	tests := []struct {
+		name  string
+		xdg   bool
+		files []string
+		want  string
+	}{
+		{name: "nothing found without XDG_CONFIG_HOME", want: ".config/tm/config.yaml"},
+		{name: "nothing found with XDG_CONFIG_HOME", xdg: true, want: "xdg/tm/config.yaml"},
+		{name: "legacy path", xdg: true, files: []string{".config/tm/config.yaml"}, want: ".config/tm/config.yaml"},
+		{name: "XDG before legacy", xdg: true, files: []string{".config/tm/config.yaml", "xdg/tm/config.json"}, want: "xdg/tm/config.json"},
+		{name: "extensions in order", xdg: true, files: []string{"xdg/tm/config.json", "xdg/tm/config.toml", "xdg/tm/config.yml"}, want: "xdg/tm/config.yml"},
+		{name: "other extensions without XDG_CONFIG_HOME", files: []string{".config/tm/config.toml"}, want: ".config/tm/config.toml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", "")
+			if tt.xdg {
+				t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+			}
+			for _, f := range tt.files {
+				path := filepath.Join(home, f)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
 
-		path, err := defaultConfigPath()
+			got, err := defaultConfigPath()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := filepath.Join(home, tt.want); got != want {
+				t.Errorf("expected %s, got %s", want, got)
+			}
+		})
+	}
+
+	t.Run("relative XDG_CONFIG_HOME is ignored", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("XDG_CONFIG_HOME", "xdg")
+		got, err := defaultConfigPath()
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-
-		expected := filepath.Join(home, ".config", "tm", "config.yaml")
-		if path != expected {
-			t.Errorf("expected %s, got %s", expected, path)
+		if want := filepath.Join(home, ".config/tm/config.yaml"); got != want {
+			t.Errorf("expected %s, got %s", want, got)
 		}
 	})
 
@@ -64,12 +124,81 @@ func TestDefaultConfigPath(t *testing.T) {
 	})
 }
 
+func TestDefaultStateDir(t *testing.T) {
+	t.Run("xdg state home", func(t *testing.T) {
+		t.Setenv("XDG_STATE_HOME", "/xdg/state")
+		got, err := defaultStateDir()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "/xdg/state/tm" {
+			t.Errorf("expected /xdg/state/tm, got %s", got)
+		}
+	})
+
+	t.Run("home fallback", func(t *testing.T) {
+		t.Setenv("XDG_STATE_HOME", "")
+		t.Setenv("HOME", "/home/me")
+		got, err := defaultStateDir()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "/home/me/.local/state/tm" {
+			t.Errorf("expected /home/me/.local/state/tm, got %s", got)
+		}
+	})
+
+	t.Run("relative xdg state home is ignored", func(t *testing.T) {
+		t.Setenv("XDG_STATE_HOME", "xdg/state")
+		t.Setenv("HOME", "/home/me")
+		got, err := defaultStateDir()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "/home/me/.local/state/tm" {
+			t.Errorf("expected /home/me/.local/state/tm, got %s", got)
+		}
+	})
+
+	t.Run("missing home", func(t *testing.T) {
+		t.Setenv("XDG_STATE_HOME", "")
+		t.Setenv("HOME", "")
+		if _, err := defaultStateDir(); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestDefaultCacheDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/xdg/cache")
+	if got, err := defaultCacheDir(); err != nil || got != "/xdg/cache/tm" {
+		t.Errorf("expected /xdg/cache/tm, got %s, %v", got, err)
+	}
+
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	if got, err := defaultCacheDir(); err != nil || got != "/home/me/.cache/tm" {
+		t.Errorf("expected /home/me/.cache/tm, got %s, %v", got, err)
+	}
+
+	t.Setenv("XDG_CACHE_HOME", "xdg/cache")
+	if got, err := defaultCacheDir(); err != nil || got != "/home/me/.cache/tm" {
+		t.Errorf("expected a relative XDG_CACHE_HOME to be ignored, got %s, %v", got, err)
+	}
+
+	t.Setenv("HOME", "")
+	if _, err := defaultCacheDir(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 func TestLoadConfigFromEnv(t *testing.T) {
 	tests := []struct {
 		name       string
 		envVars    map[string]string
 		wantDebug  bool
 		wantTmux   string
+		wantSocket string
 		wantFzf    string
 		wantConfig string
 		wantErr    bool
@@ -88,11 +217,13 @@ func TestLoadConfigFromEnv(t *testing.T) {
 			envVars: map[string]string{
 				"TM_DEBUG":       "true",
 				"TM_TMUX_PATH":   "/usr/bin/tmux",
+				"TM_TMUX_SOCKET": "work",
 				"TM_FZF_PATH":    "/usr/bin/fzf",
 				"TM_CONFIG_PATH": "/custom/config.yaml",
 			},
 			wantDebug:  true,
 			wantTmux:   "/usr/bin/tmux",
+			wantSocket: "work",
 			wantFzf:    "/usr/bin/fzf",
 			wantConfig: "/custom/config.yaml",
 			wantErr:    false,
@@ -119,7 +250,7 @@ func TestLoadConfigFromEnv(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			for _, k := range []string{"TM_DEBUG", "TM_TMUX_PATH", "TM_FZF_PATH", "TM_CONFIG_PATH"} {
+			for _, k := range []string{"TM_DEBUG", "TM_TMUX_PATH", "TM_TMUX_SOCKET", "TM_FZF_PATH", "TM_PICKER", "TM_CONFIG_PATH"} {
 				t.Setenv(k, "")
 			}
 			for k, v := range tt.envVars {
@@ -143,6 +274,9 @@ func TestLoadConfigFromEnv(t *testing.T) {
 			if cfg.TmuxPath != tt.wantTmux {
 				t.Errorf("TmuxPath = %s, want %s", cfg.TmuxPath, tt.wantTmux)
 			}
+			if cfg.TmuxSocket != tt.wantSocket {
+				t.Errorf("TmuxSocket = %s, want %s", cfg.TmuxSocket, tt.wantSocket)
+			}
 			if cfg.FzfPath != tt.wantFzf {
 				t.Errorf("FzfPath = %s, want %s", cfg.FzfPath, tt.wantFzf)
 			}
@@ -206,7 +340,7 @@ sessions: [
 				t.Fatalf("failed to write test file: %v", err)
 			}
 
-			cfg, err := loadConfigFromConfigFile(configPath, configPath)
+			cfg, err := loadConfigFromConfigFile(configPath, configPath, "", true)
 			if tt.wantErr {
 				if err == nil {
 					t.Error("expected error, got nil")
@@ -229,7 +363,7 @@ sessions: [
 	t.Run("missing custom path", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		path := filepath.Join(tmpDir, "does-not-exist.yaml")
-		_, err := loadConfigFromConfigFile(path, filepath.Join(tmpDir, "default.yaml"))
+		_, err := loadConfigFromConfigFile(path, filepath.Join(tmpDir, "default.yaml"), "", true)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -241,7 +375,7 @@ sessions: [
 	t.Run("missing default path creates empty config", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		defaultPath := filepath.Join(tmpDir, "config.yaml")
-		cfg, err := loadConfigFromConfigFile(defaultPath, defaultPath)
+		cfg, err := loadConfigFromConfigFile(defaultPath, defaultPath, "", true)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -253,6 +387,21 @@ sessions: [
 		}
 	})
 
+	t.Run("missing default path is not created when turned off", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		defaultPath := filepath.Join(tmpDir, "tm", "config.yaml")
+		cfg, err := loadConfigFromConfigFile(defaultPath, defaultPath, "", false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.PreDefinedSessions) != 0 || len(cfg.SmartDirectories) != 0 {
+			t.Errorf("expected empty config, got %+v", cfg)
+		}
+		if _, err := os.Stat(filepath.Dir(defaultPath)); !os.IsNotExist(err) {
+			t.Errorf("expected nothing to be created, got %v", err)
+		}
+	})
+
 	t.Run("missing default path create fails mkdir", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		blockingFile := filepath.Join(tmpDir, "blocking")
@@ -260,7 +409,7 @@ sessions: [
 			t.Fatal(err)
 		}
 		defaultPath := filepath.Join(blockingFile, "config.yaml")
-		_, err := loadConfigFromConfigFile(defaultPath, defaultPath)
+		_, err := loadConfigFromConfigFile(defaultPath, defaultPath, "", true)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -279,7 +428,7 @@ sessions: [
 			t.Fatal(err)
 		}
 		defer os.Chmod(tmpDir, 0755)
-		_, err := loadConfigFromConfigFile(defaultPath, defaultPath)
+		_, err := loadConfigFromConfigFile(defaultPath, defaultPath, "", true)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -303,7 +452,7 @@ sessions: [
 		}
 		defer os.Chmod(subDir, 0755)
 
-		_, err := loadConfigFromConfigFile(configPath, filepath.Join(tmpDir, "default.yaml"))
+		_, err := loadConfigFromConfigFile(configPath, filepath.Join(tmpDir, "default.yaml"), "", true)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -324,7 +473,7 @@ func TestLoad(t *testing.T) {
 		t.Setenv("TM_TMUX_PATH", "/nonexistent/tmux")
 		t.Setenv("TM_CONFIG_PATH", configPath)
 
-		cfg, err := Load()
+		cfg, err := Load("")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -343,7 +492,7 @@ func TestLoad(t *testing.T) {
 		t.Setenv("TM_FZF_PATH", "/nonexistent/fzf")
 		t.Setenv("TM_CONFIG_PATH", configPath)
 
-		cfg, err := Load()
+		cfg, err := Load("")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -371,7 +520,7 @@ smart_directories:
 		t.Setenv("TM_DEBUG", "true")
 		t.Setenv("TM_CONFIG_PATH", configPath)
 
-		cfg, err := Load()
+		cfg, err := Load("")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -386,9 +535,216 @@ smart_directories:
 		}
 	})
 
+	t.Run("sessions with windows", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		content := `
+sessions:
+  - dir: ~/projects/app1
+    name: app1
+    windows:
+      - name: editor
+        layout: main-vertical
+        panes:
+          - dir: .
+          - dir: api
+      - name: logs
+        dir: /var/log
+        command: tail -f syslog
+        enter: false
+      - name: shell
+        panes:
+          - command: go test ./...
+            enter: true
+`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("TM_CONFIG_PATH", configPath)
+
+		cfg, err := Load("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		windows := cfg.PreDefinedSessions[0].Windows
+		if len(windows) != 3 {
+			t.Fatalf("expected 3 windows, got %v", windows)
+		}
+		if windows[0].Name != "editor" || windows[0].Layout != "main-vertical" || len(windows[0].Panes) != 2 {
+			t.Errorf("unexpected editor window %+v", windows[0])
+		}
+		if windows[0].Panes[1].Dir != "api" {
+			t.Errorf("expected second pane dir api, got %s", windows[0].Panes[1].Dir)
+		}
+		if windows[1].Name != "logs" || windows[1].Dir != "/var/log" || len(windows[1].Panes) != 0 {
+			t.Errorf("unexpected logs window %+v", windows[1])
+		}
+		if windows[1].Command != "tail -f syslog" || !windows[1].NoEnter {
+			t.Errorf("expected logs command without enter, got %+v", windows[1])
+		}
+		if windows[0].NoEnter || windows[0].Panes[0].NoEnter {
+			t.Error("expected enter to default to true")
+		}
+		if p := windows[2].Panes[0]; p.Command != "go test ./..." || p.NoEnter {
+			t.Errorf("unexpected shell pane %+v", p)
+		}
+	})
+
+	t.Run("hooks on sessions and smart directories", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		content := `
+sessions:
+  - dir: ~/projects/app1
+    name: app1
+    on_create:
+      - direnv allow
+      - command: nvm use
+        abort: true
+    on_attach:
+      - vpn up
+    on_detach:
+      - vpn down
+    on_kill:
+      - docker compose down
+smart_directories:
+  - ~/projects
+  - dir: ~/work
+    on_attach:
+      - command: kubectx work
+        abort: true
+`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("TM_CONFIG_PATH", configPath)
+
+		cfg, err := Load("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		hooks := cfg.PreDefinedSessions[0].Hooks
+		if len(hooks.OnCreate) != 2 || hooks.OnCreate[0] != (session.Hook{Command: "direnv allow"}) || hooks.OnCreate[1] != (session.Hook{Command: "nvm use", Abort: true}) {
+			t.Errorf("unexpected on_create hooks %v", hooks.OnCreate)
+		}
+		if len(hooks.OnAttach) != 1 || len(hooks.OnDetach) != 1 || len(hooks.OnKill) != 1 {
+			t.Errorf("unexpected hooks %+v", hooks)
+		}
+
+		if len(cfg.SmartDirectories) != 2 {
+			t.Fatalf("expected 2 smart directories, got %v", cfg.SmartDirectories)
+		}
+		if cfg.SmartDirectories[0].Dir != "~/projects" || len(cfg.SmartDirectories[0].Hooks.OnAttach) != 0 {
+			t.Errorf("unexpected plain smart directory %+v", cfg.SmartDirectories[0])
+		}
+		if cfg.SmartDirectories[1].Dir != "~/work" || len(cfg.SmartDirectories[1].Hooks.OnAttach) != 1 || !cfg.SmartDirectories[1].Hooks.OnAttach[0].Abort {
+			t.Errorf("unexpected smart directory %+v", cfg.SmartDirectories[1])
+		}
+	})
+
+	t.Run("env on sessions and smart directories", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		content := `
+sessions:
+  - dir: ~/projects/app1
+    name: app1
+    env:
+      AWS_PROFILE: work
+      KUBECONFIG: ~/.kube/work
+smart_directories:
+  - dir: ~/work
+    env:
+      GOFLAGS: -mod=mod
+`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("TM_CONFIG_PATH", configPath)
+
+		cfg, err := Load("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if env := cfg.PreDefinedSessions[0].Env; env["AWS_PROFILE"] != "work" || env["KUBECONFIG"] != "~/.kube/work" {
+			t.Errorf("unexpected session env %v", env)
+		}
+		if env := cfg.SmartDirectories[0].Env; env["GOFLAGS"] != "-mod=mod" {
+			t.Errorf("unexpected smart directory env %v", env)
+		}
+	})
+
+	t.Run("scanning options on smart directories", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		content := `
+smart_directories:
+  - ~/projects
+  - dir: ~/src
+    depth: 3
+    markers:
+      - .git
+      - go.mod
+    ignore:
+      - node_modules
+      - archive/
+    include:
+      - work-*
+    show_hidden: true
+    prefix: "src-"
+    name_template: "{parent}/{base}"
+`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("TM_CONFIG_PATH", configPath)
+
+		cfg, err := Load("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sd := cfg.SmartDirectories[0]; sd.Depth != 0 || len(sd.Markers) != 0 || sd.ShowHidden {
+			t.Errorf("unexpected plain smart directory %+v", sd)
+		}
+		if sd := cfg.SmartDirectories[1]; sd.Depth != 3 || len(sd.Markers) != 2 || sd.Markers[1] != "go.mod" {
+			t.Errorf("unexpected smart directory %+v", sd)
+		}
+		if sd := cfg.SmartDirectories[1]; len(sd.Ignore) != 2 || sd.Ignore[1] != "archive/" || len(sd.Include) != 1 || !sd.ShowHidden {
+			t.Errorf("unexpected smart directory filters %+v", sd)
+		}
+		if sd := cfg.SmartDirectories[1]; sd.Prefix != "src-" || sd.NameTemplate != "{parent}/{base}" {
+			t.Errorf("unexpected smart directory naming %+v", sd)
+		}
+	})
+
+	t.Run("config path argument overrides env", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		envPath := filepath.Join(tmpDir, "env.yaml")
+		argPath := filepath.Join(tmpDir, "arg.yaml")
+		if err := os.WriteFile(envPath, []byte("sessions:\n  - dir: ~/env\n    name: env\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(argPath, []byte("sessions:\n  - dir: ~/arg\n    name: arg\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("TM_CONFIG_PATH", envPath)
+
+		cfg, err := Load(argPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.ConfigPath != argPath {
+			t.Errorf("expected ConfigPath %s, got %s", argPath, cfg.ConfigPath)
+		}
+		if len(cfg.PreDefinedSessions) != 1 || cfg.PreDefinedSessions[0].Name != "arg" {
+			t.Errorf("expected sessions from %s, got %+v", argPath, cfg.PreDefinedSessions)
+		}
+	})
+
 	t.Run("loadConfigFromEnv error", func(t *testing.T) {
 		t.Setenv("TM_DEBUG", "not-a-bool")
-		_, err := Load()
+		_, err := Load("")
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -396,7 +752,7 @@ smart_directories:
 
 	t.Run("defaultConfigPath error", func(t *testing.T) {
 		t.Setenv("HOME", "")
-		_, err := Load()
+		_, err := Load("")
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -409,13 +765,224 @@ smart_directories:
 			t.Fatal(err)
 		}
 		t.Setenv("TM_CONFIG_PATH", configPath)
-		_, err := Load()
+		_, err := Load("")
 		if err == nil {
 			t.Fatal("expected error")
 		}
 	})
 }
 
+func TestLoad_Picker(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{name: "defaults to auto", want: PickerAuto},
+		{name: "from config file", content: "picker: builtin\n", want: PickerBuiltin},
+		{name: "env overrides config file", content: "picker: builtin\n", env: "fzf", want: PickerFzf},
+		{name: "invalid", content: "picker: skim\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("TM_PICKER", tt.env)
+
+			cfg, err := Load(configPath)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid picker") {
+					t.Fatalf("expected invalid picker error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Picker != tt.want {
+				t.Errorf("Picker = %s, want %s", cfg.Picker, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_Match(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Match
+		wantErr bool
+	}{
+		{name: "defaults to ranked", want: Match{Strategy: MatchRanked}},
+		{name: "prefix with dirs", content: "match:\n  strategy: prefix\n  dirs: true\n", want: Match{Strategy: MatchPrefix, Dirs: true}},
+		{name: "invalid", content: "match:\n  strategy: fuzzy\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(configPath)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid match strategy") {
+					t.Fatalf("expected invalid match strategy error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Match != tt.want {
+				t.Errorf("Match = %+v, want %+v", cfg.Match, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_Popup(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Popup
+		wantErr string
+	}{
+		{name: "defaults", want: Popup{Width: "80%", Height: "80%"}},
+		{
+			name:    "all settings",
+			content: "popup:\n  width: 120\n  height: 50%\n  border: rounded\n  always: true\n",
+			want:    Popup{Width: "120", Height: "50%", Border: "rounded", Always: true},
+		},
+		{name: "invalid width", content: "popup:\n  width: wide\n", wantErr: "invalid popup width"},
+		{name: "invalid height", content: "popup:\n  height: 0\n", wantErr: "invalid popup height"},
+		{name: "invalid border", content: "popup:\n  border: dotted\n", wantErr: "invalid popup border"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Popup != tt.want {
+				t.Errorf("Popup = %+v, want %+v", cfg.Popup, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_Socket(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "sessions:\n  - name: api\n    dir: /src/api\n    socket: work\n  - name: web\n    dir: /src/web\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TM_TMUX_SOCKET", "/tmp/tmux.sock")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.TmuxSocket != "/tmp/tmux.sock" {
+		t.Errorf("TmuxSocket = %s, want /tmp/tmux.sock", cfg.TmuxSocket)
+	}
+	if len(cfg.PreDefinedSessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(cfg.PreDefinedSessions))
+	}
+	if got := cfg.PreDefinedSessions[0].Socket; got != "work" {
+		t.Errorf("api Socket = %q, want work", got)
+	}
+	if got := cfg.PreDefinedSessions[1].Socket; got != "" {
+		t.Errorf("web Socket = %q, want empty", got)
+	}
+}
+
+func TestLoad_Warnings(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("smart_directory:\n  - ~/src\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, debug := range []string{"false", "true"} {
+		t.Run("TM_DEBUG="+debug, func(t *testing.T) {
+			t.Setenv("TM_DEBUG", debug)
+
+			cfg, err := Load(configPath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var want []Problem
+			if debug == "true" {
+				want = []Problem{{File: configPath, Line: 1, Message: `unknown key "smart_directory"`}}
+			}
+			if !slices.Equal(cfg.Warnings, want) {
+				t.Errorf("Warnings = %v, want %v", cfg.Warnings, want)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("TM_CONFIG_PATH", "/env/config.yaml")
+	if got, _ := Path("/flag/config.yaml"); got != "/flag/config.yaml" {
+		t.Errorf("Path with a flag = %s, want /flag/config.yaml", got)
+	}
+	if got, _ := Path(""); got != "/env/config.yaml" {
+		t.Errorf("Path with TM_CONFIG_PATH = %s, want /env/config.yaml", got)
+	}
+
+	t.Setenv("TM_CONFIG_PATH", "")
+	want, err := defaultConfigPath()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	if got, _ := Path(""); got != want {
+		t.Errorf("Path = %s, want %s", got, want)
+	}
+}
+
+func TestLoad_CreateConfig(t *testing.T) {
+	for _, noCreate := range []string{"", "true"} {
+		t.Run("TM_NO_CREATE_CONFIG="+noCreate, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+			t.Setenv("TM_CONFIG_PATH", "")
+			t.Setenv("TM_NO_CREATE_CONFIG", noCreate)
+
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := filepath.Join(home, "xdg", "tm", "config.yaml")
+			if cfg.ConfigPath != want {
+				t.Errorf("ConfigPath = %s, want %s", cfg.ConfigPath, want)
+			}
+			if _, err := os.Stat(want); (err == nil) != (noCreate == "") {
+				t.Errorf("config file created = %v, want %v", err == nil, noCreate == "")
+			}
+		})
+	}
+}
+
 func TestResolveBinaryPath(t *testing.T) {
 	t.Run("valid env path", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/griggsjared/tm/internal/atomicfile"
+)
+
+// File edits the config file in place. Edits replace only the bytes of the
+// value they change, so comments and layout are left exactly as they were.
+type File struct {
+	path string
+}
+
+func NewFile(path string) *File {
+	return &File{
+		path: path,
+	}
+}
+
+func (f *File) Path() string {
+	return f.path
+}
+
+// SessionFiles returns the files defining a session named name, in the
+// order they are merged: the config file, the files it includes and the
+// host overlay. It returns nothing when no file defines the session.
+func (f *File) SessionFiles(name string) ([]string, error) {
+	envConfig, err := loadConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	layers, err := readLayers(f.path, resolveProfile(envConfig.Profile))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, l := range layers {
+		if sessionName(l.root, name) != nil {
+			files = append(files, l.path)
+		}
+	}
+	return files, nil
+}
+
+// RenameSession changes the name of the session defined as oldName in every
+// file that defines it, so a definition another file overrides does not come
+// back under the old name.
+func (f *File) RenameSession(oldName, newName string) error {
+	files, err := f.SessionFiles(oldName)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("session %q is not defined in %s", oldName, f.path)
+	}
+	for _, path := range files {
+		if formatOf(path) == FormatTOML {
+			return fmt.Errorf("renaming sessions in TOML config files is not supported, edit %s instead", path)
+		}
+	}
+
+	for _, path := range files {
+		if err := NewFile(path).renameSession(oldName, newName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameSession changes the name of the session defined as oldName in this
+// file alone.
+func (f *File) renameSession(oldName, newName string) error {
+	content, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	node, err := findSessionName(f.path, content, oldName)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return fmt.Errorf("session %q is not defined in %s", oldName, f.path)
+	}
+
+	start, end, err := scalarSpan(content, node)
+	if err != nil {
+		return err
+	}
+	replacement, err := encodeScalar(newName, node.Style)
+	if err != nil {
+		return err
+	}
+
+	var updated bytes.Buffer
+	updated.Write(content[:start])
+	updated.WriteString(replacement)
+	updated.Write(content[end:])
+
+	// Make sure the edit landed where it was meant to before writing it.
+	if check, err := findSessionName(f.path, updated.Bytes(), newName); err != nil || check == nil {
+		return fmt.Errorf("failed to rename session %q in %s", oldName, f.path)
+	}
+
+	return f.write(updated.Bytes())
+}
+
+func (f *File) read() ([]byte, error) {
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("config file is inaccessible: %w", err)
+	}
+	return content, nil
+}
+
+func (f *File) write(content []byte) error {
+	// Keep the mode of the file, or use the one a new config file gets.
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(f.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := atomicfile.Write(f.path, content, mode); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// findSessionName returns the value node of the name key of the session
+// named name, or nil when no session has that name.
+func findSessionName(path string, content []byte, name string) (*yaml.Node, error) {
+	root, err := parseDocument(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+	if root == nil {
+		return nil, nil
+	}
+	return sessionName(root, name), nil
+}
+
+// sessionName returns the value node of the name key of the session named
+// name in the parsed config file root, or nil when no session has that name.
+func sessionName(root *yaml.Node, name string) *yaml.Node {
+	sessions := mappingValue(root, "sessions")
+	if sessions == nil || sessions.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, s := range sessions.Content {
+		if n := mappingValue(s, "name"); n != nil && n.Kind == yaml.ScalarNode && n.Value == name {
+			return n
+		}
+	}
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarSpan returns the byte offsets of a single-line scalar in content,
+// including its quotes.
+func scalarSpan(content []byte, node *yaml.Node) (int, int, error) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if node.Line < 1 || node.Line > len(lines) {
+		return 0, 0, fmt.Errorf("failed to locate line %d in config file", node.Line)
+	}
+
+	start := 0
+	for _, line := range lines[:node.Line-1] {
+		start += len(line)
+	}
+	// yaml.v3 counts columns in characters, not bytes.
+	line := []rune(string(lines[node.Line-1]))
+	if node.Column < 1 || node.Column > len(line) {
+		return 0, 0, fmt.Errorf("failed to locate column %d in config file", node.Column)
+	}
+	start += len(string(line[:node.Column-1]))
+
+	rest := content[start:]
+	switch node.Style {
+	case 0, yaml.TaggedStyle:
+		if !bytes.HasPrefix(rest, []byte(node.Value)) {
+			return 0, 0, fmt.Errorf("failed to locate %q in config file", node.Value)
+		}
+		return start, start + len(node.Value), nil
+	case yaml.DoubleQuotedStyle:
+		for i := 1; i < len(rest) && rest[i] != '\n'; i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '"':
+				return start, start + i + 1, nil
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := 1; i < len(rest) && rest[i] != '\n'; i++ {
+			if rest[i] != '\'' {
+				continue
+			}
+			if i+1 < len(rest) && rest[i+1] == '\'' {
+				i++
+				continue
+			}
+			return start, start + i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("cannot edit %q in config file, write it on a single line", node.Value)
+}
+
+// encodeScalar writes value in the same quoting style as the value it
+// replaces, falling back to quotes where a plain value would be misread.
+func encodeScalar(value string, style yaml.Style) (string, error) {
+	switch style {
+	case yaml.DoubleQuotedStyle:
+		return strconv.Quote(value), nil
+	case yaml.SingleQuotedStyle:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'", nil
+	}
+
+	encoded, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(encoded), "\n"), nil
+}
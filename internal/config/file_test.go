@@ -0,0 +1,205 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const fileTestConfig = `# Sessions I use every day
+sessions:
+  -
+    dir: ~/projects/api   # the backend
+    name: api
+    aliases:
+      - be
+  - dir: ~/projects/web
+    name: "web"  # quoted on purpose
+  - { dir: ~/notes, name: 'notes', aliases: [n] }
+  - { dir: ~/café, name: café }
+
+smart_directories:
+  - ~/projects   # name: api is not a session
+`
+
+func writeFileTestConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(fileTestConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFile_SessionFiles(t *testing.T) {
+	path := writeFileTestConfig(t)
+	f := NewFile(path)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "api", want: []string{path}},
+		{name: "web", want: []string{path}},
+		{name: "notes", want: []string{path}},
+		{name: "be", want: nil},
+		{name: "missing", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.SessionFiles(tt.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SessionFiles(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := NewFile(filepath.Join(t.TempDir(), "missing.yaml")).SessionFiles("api"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestFile_RenameSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldName string
+		newName string
+		line    string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "plain value keeps comments",
+			oldName: "api",
+			newName: "backend",
+			line:    "    name: api\n",
+			want:    "    name: backend\n",
+		},
+		{
+			name:    "double quoted value stays quoted",
+			oldName: "web",
+			newName: "frontend",
+			line:    "    name: \"web\"  # quoted on purpose\n",
+			want:    "    name: \"frontend\"  # quoted on purpose\n",
+		},
+		{
+			name:    "flow mapping with single quotes",
+			oldName: "notes",
+			newName: "it's notes",
+			line:    "  - { dir: ~/notes, name: 'notes', aliases: [n] }\n",
+			want:    "  - { dir: ~/notes, name: 'it''s notes', aliases: [n] }\n",
+		},
+		{
+			name:    "multi-byte characters before the value",
+			oldName: "café",
+			newName: "coffee",
+			line:    "  - { dir: ~/café, name: café }\n",
+			want:    "  - { dir: ~/café, name: coffee }\n",
+		},
+		{
+			name:    "plain value that needs quotes",
+			oldName: "api",
+			newName: "123",
+			line:    "    name: api\n",
+			want:    "    name: \"123\"\n",
+		},
+		{
+			name:    "unknown session",
+			oldName: "missing",
+			newName: "other",
+			wantErr: "is not defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFileTestConfig(t)
+			err := NewFile(path).RenameSession(tt.oldName, tt.newName)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Apart from the renamed value the file is byte for byte the same.
+			if want := strings.Replace(fileTestConfig, tt.line, tt.want, 1); string(content) != want {
+				t.Errorf("unexpected file content:\n%s\nwant:\n%s", content, want)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("expected file mode to be kept, got %v", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestFile_RenameSession_BlockScalar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "sessions:\n  - dir: ~/api\n    name: >-\n      api\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFile(path).RenameSession("api", "backend"); err == nil {
+		t.Fatal("expected error for a block scalar name")
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != content {
+		t.Errorf("expected file to be left untouched, got:\n%s", got)
+	}
+}
+
+func TestFile_RenameSession_Includes(t *testing.T) {
+	t.Setenv("TM_PROFILE", "laptop")
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml":        "include: [work.yaml]\nsessions:\n  - { name: web, dir: ~/web }\n",
+		"work.yaml":          "sessions:\n  - name: api # the backend\n    dir: ~/api\n",
+		"config.laptop.yaml": "sessions:\n  - { name: api, dir: ~/src/api }\n",
+	})
+	f := NewFile(filepath.Join(dir, "config.yaml"))
+
+	files, err := f.SessionFiles("api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{filepath.Join(dir, "work.yaml"), filepath.Join(dir, "config.laptop.yaml")}
+	if !slices.Equal(files, want) {
+		t.Fatalf("SessionFiles(api) = %v, want %v", files, want)
+	}
+
+	if err := f.RenameSession("api", "backend"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, content := range map[string]string{
+		"config.yaml":        "include: [work.yaml]\nsessions:\n  - { name: web, dir: ~/web }\n",
+		"work.yaml":          "sessions:\n  - name: backend # the backend\n    dir: ~/api\n",
+		"config.laptop.yaml": "sessions:\n  - { name: backend, dir: ~/src/api }\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("unexpected content of %s:\n%s\nwant:\n%s", name, got, content)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Config file formats, named after the extension of the file.
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
+
+// Formats lists every config file format.
+var Formats = []string{FormatYAML, FormatTOML, FormatJSON}
+
+// formatOf returns the format of the config file at path. Files with an
+// unknown extension are read as YAML, as they always were.
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return FormatTOML
+	case ".json":
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// parseDocument parses a config file in the format of path into the root
+// node of a YAML document, or nil for an empty one. Every format shares the
+// fileConfig schema this way, and the merging and checks built on YAML
+// nodes. JSON is read as the YAML it is a subset of, so its nodes keep their
+// line numbers. TOML values have none.
+func parseDocument(path string, content []byte) (*yaml.Node, error) {
+	if formatOf(path) == FormatTOML {
+		var v map[string]any
+		if err := toml.Unmarshal(content, &v); err != nil {
+			return nil, err
+		}
+		if len(v) == 0 {
+			return nil, nil
+		}
+		var root yaml.Node
+		if err := root.Encode(v); err != nil {
+			return nil, err
+		}
+		return &root, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Tag == "!!null" {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// Convert returns the config file at path written in format. Comments are
+// lost, and keys come out in the order the format's encoder picks.
+func (f *File) Convert(format string) ([]byte, error) {
+	if !slices.Contains(Formats, format) {
+		return nil, fmt.Errorf("invalid format %q, want one of %s", format, strings.Join(Formats, ", "))
+	}
+
+	content, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseDocument(f.path, content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+
+	v := map[string]any{}
+	if root != nil {
+		if err := root.Decode(&v); err != nil {
+			return nil, fmt.Errorf("invalid config file: %w", err)
+		}
+	}
+	v = dropNulls(v).(map[string]any)
+
+	switch format {
+	case FormatTOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, fmt.Errorf("failed to write TOML: %w", err)
+		}
+		return buf.Bytes(), nil
+	case FormatJSON:
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to write JSON: %w", err)
+		}
+		return append(out, '\n'), nil
+	}
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write YAML: %w", err)
+	}
+	return out, nil
+}
+
+// dropNulls removes empty YAML values, such as a key without a value, which
+// TOML has no way to write.
+func dropNulls(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			if item != nil {
+				out[k] = dropNulls(item)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				out = append(out, dropNulls(item))
+			}
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const formatYAML = `picker: fzf
+popup:
+  width: 60%
+sessions:
+  - name: api
+    dir: /src/api
+    aliases: [a]
+    windows:
+      - name: editor
+        command: nvim .
+  - name: api
+    dir: /src/api-work
+    socket: work
+smart_directories:
+  - ~/src
+  - dir: ~/work
+    depth: 2
+`
+
+const formatTOML = `picker = "fzf"
+smart_directories = ["~/src", { dir = "~/work", depth = 2 }]
+
+[popup]
+width = "60%"
+
+[[sessions]]
+name = "api"
+dir = "/src/api"
+aliases = ["a"]
+
+[[sessions.windows]]
+name = "editor"
+command = "nvim ."
+
+[[sessions]]
+name = "api"
+dir = "/src/api-work"
+socket = "work"
+`
+
+const formatJSON = `{
+  "picker": "fzf",
+  "popup": {"width": "60%"},
+  "sessions": [
+    {"name": "api", "dir": "/src/api", "aliases": ["a"], "windows": [{"name": "editor", "command": "nvim ."}]},
+    {"name": "api", "dir": "/src/api-work", "socket": "work"}
+  ],
+  "smart_directories": ["~/src", {"dir": "~/work", "depth": 2}]
+}
+`
+
+func TestLoad_Formats(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": formatYAML,
+		"config.toml": formatTOML,
+		"config.json": formatJSON,
+	})
+	t.Setenv("TM_PROFILE", "none")
+
+	want, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(want.PreDefinedSessions) != 2 || len(want.SmartDirectories) != 2 {
+		t.Fatalf("unexpected YAML config %+v", want)
+	}
+
+	for _, name := range []string{"config.toml", "config.json"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Load(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Picker != want.Picker || got.Popup != want.Popup {
+				t.Errorf("settings = %s %+v, want %s %+v", got.Picker, got.Popup, want.Picker, want.Popup)
+			}
+			if !reflect.DeepEqual(got.PreDefinedSessions, want.PreDefinedSessions) {
+				t.Errorf("sessions = %+v, want %+v", got.PreDefinedSessions, want.PreDefinedSessions)
+			}
+			if !reflect.DeepEqual(got.SmartDirectories, want.SmartDirectories) {
+				t.Errorf("smart directories = %+v, want %+v", got.SmartDirectories, want.SmartDirectories)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidTOML(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"config.toml": "picker = \n"})
+	t.Setenv("TM_PROFILE", "none")
+
+	_, err := Load(filepath.Join(dir, "config.toml"))
+	if err == nil || !strings.Contains(err.Error(), "invalid config file") {
+		t.Fatalf("expected an invalid config file error, got %v", err)
+	}
+}
+
+func TestFile_Check_Formats(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.toml": "picker = \"fzf\"\nsmart_directory = [\"~/src\"]\n\n[[sessions]]\nname = \"api\"\n\n[[sessions]]\nname = \"api\"\ndir = \"/\"\n",
+		"config.json": "{\n  \"picker\": \"fzf\",\n  \"smart_directory\": [\"~/src\"]\n}\n",
+	})
+
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{
+			file: "config.toml",
+			want: []string{
+				`config.toml: unknown key "smart_directory"`,
+				`config.toml: session name "api" is already used`,
+				`config.toml: session "api": dir is missing`,
+			},
+		},
+		{
+			file: "config.json",
+			want: []string{`config.json:3: unknown key "smart_directory"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			problems, err := NewFile(filepath.Join(dir, tt.file)).Check()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, p := range problems {
+				p.File = filepath.Base(p.File)
+				got = append(got, p.String())
+			}
+			for _, w := range tt.want {
+				if !slices.Contains(got, w) {
+					t.Errorf("expected problem %q, got %q", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFile_Convert(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": "# comment\n" + formatYAML + "match:\n",
+	})
+	t.Setenv("TM_PROFILE", "none")
+
+	want, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			out, err := NewFile(filepath.Join(dir, "config.yaml")).Convert(format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			converted := writeConfigFiles(t, map[string]string{"config." + format: string(out)})
+
+			got, err := Load(filepath.Join(converted, "config."+format))
+			if err != nil {
+				t.Fatalf("failed to load converted config: %v\n%s", err, out)
+			}
+			if !reflect.DeepEqual(got.PreDefinedSessions, want.PreDefinedSessions) || !reflect.DeepEqual(got.SmartDirectories, want.SmartDirectories) {
+				t.Errorf("converted config differs:\n%s", out)
+			}
+		})
+	}
+
+	if _, err := NewFile(filepath.Join(dir, "config.yaml")).Convert("ini"); err == nil || !strings.Contains(err.Error(), "invalid format") {
+		t.Errorf("expected an invalid format error, got %v", err)
+	}
+}
+
+func TestFile_RenameSession_TOML(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{"config.toml": formatTOML})
+	f := NewFile(filepath.Join(dir, "config.toml"))
+
+	if files, err := f.SessionFiles("api"); err != nil || len(files) != 1 {
+		t.Fatalf("SessionFiles(api) = %v, %v, want the config file", files, err)
+	}
+	if err := f.RenameSession("api", "backend"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected renaming in TOML to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// layer is one config file taking part in the merged config.
+type layer struct {
+	path string
+	root *yaml.Node
+}
+
+// readLayers reads the config file at path, the files it includes and the
+// overlay for profile, in the order they are merged. Included files come
+// right after the file including them, so they can add to it and override
+// it, and the overlay comes last. Each file is read once, however often it
+// is included.
+func readLayers(path, profile string) ([]layer, error) {
+	seen := make(map[string]bool)
+	layers, err := readLayer(path, seen)
+	if err != nil {
+		return nil, err
+	}
+
+	if overlay := overlayPath(path, profile); overlay != "" {
+		if _, err := os.Stat(overlay); err == nil {
+			more, err := readLayer(overlay, seen)
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, more...)
+		}
+	}
+	return layers, nil
+}
+
+func readLayer(path string, seen map[string]bool) ([]layer, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if seen[abs] {
+		return nil, nil
+	}
+	seen[abs] = true
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file is inaccessible: %w", err)
+	}
+	root, err := parseDocument(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if root == nil {
+		return nil, nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid config file %s: line %d: the config file must be a mapping of settings", path, root.Line)
+	}
+
+	layers := []layer{{path: path, root: root}}
+	include := mappingValue(root, "include")
+	if include == nil {
+		return layers, nil
+	}
+	var patterns []string
+	if err := include.Decode(&patterns); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	for i, pattern := range patterns {
+		item := include.Content[i]
+		files, err := includedFiles(filepath.Dir(path), pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %s", path, Problem{Line: item.Line, Message: err.Error()})
+		}
+		for _, file := range files {
+			more, err := readLayer(file, seen)
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, more...)
+		}
+	}
+	return layers, nil
+}
+
+// decodeLayers decodes each file on its own, so a mistake is reported with
+// the file it is in rather than somewhere in the merged config.
+func decodeLayers(layers []layer) error {
+	for _, l := range layers {
+		var c fileConfig
+		if err := l.root.Decode(&c); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", l.path, err)
+		}
+	}
+	return nil
+}
+
+// includedFiles resolves an include entry against dir, the directory of the
+// including file. A pattern with glob characters may match nothing, but a
+// plain path has to exist.
+func includedFiles(dir, pattern string) ([]string, error) {
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine home directory: %w", err)
+		}
+		pattern = filepath.Join(home, pattern[1:])
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, fmt.Errorf("included file %s does not exist", pattern)
+		}
+		return []string{pattern}, nil
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+	}
+	return files, nil
+}
+
+// overlayPath returns the overlay of the config file at path for profile,
+// config.laptop.yaml for config.yaml, or an empty string without a profile.
+func overlayPath(path, profile string) string {
+	if profile == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// resolveProfile returns the profile choosing the overlay: TM_PROFILE when
+// it is set, otherwise the host name up to its first dot.
+func resolveProfile(env string) string {
+	if env != "" {
+		return env
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	host, _, _ = strings.Cut(host, ".")
+	return host
+}
+
+// source is where a merged value was defined.
+type source struct {
+	path string
+	line int
+}
+
+func (s source) String() string {
+	if s.line == 0 {
+		return s.path
+	}
+	return fmt.Sprintf("%s:%d", s.path, s.line)
+}
+
+// merger merges layers into a single mapping. Sessions are merged by name,
+// and by socket for sessions on other tmux servers, with a later definition
+// replacing an earlier one. Smart directories are appended. Any other
+// setting is replaced, key by key for mappings such as popup.
+type merger struct {
+	root    *yaml.Node
+	sources map[*yaml.Node]source
+}
+
+func mergeLayers(layers []layer) *merger {
+	m := &merger{
+		root:    &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		sources: make(map[*yaml.Node]source),
+	}
+	for _, l := range layers {
+		m.add(l)
+	}
+	return m
+}
+
+func (m *merger) add(l layer) {
+	for i := 0; i+1 < len(l.root.Content); i += 2 {
+		key, value := l.root.Content[i], l.root.Content[i+1]
+		switch key.Value {
+		case "include":
+			continue
+		case "sessions", "smart_directories":
+			target := mappingValue(m.root, key.Value)
+			if value.Kind != yaml.SequenceNode || (target != nil && target.Kind != yaml.SequenceNode) {
+				m.set(m.root, l.path, key, value)
+				continue
+			}
+			if target == nil {
+				target = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+				m.root.Content = append(m.root.Content, key, target)
+			}
+			for _, item := range value.Content {
+				m.sources[item] = source{path: l.path, line: item.Line}
+				if key.Value == "sessions" {
+					m.addSession(target, item)
+				} else {
+					target.Content = append(target.Content, item)
+				}
+			}
+		default:
+			m.set(m.root, l.path, key, value)
+		}
+	}
+}
+
+func (m *merger) addSession(sessions, item *yaml.Node) {
+	id := sessionID(item)
+	if id != "" {
+		for i, existing := range sessions.Content {
+			if sessionID(existing) == id {
+				sessions.Content[i] = item
+				return
+			}
+		}
+	}
+	sessions.Content = append(sessions.Content, item)
+}
+
+// sessionID identifies a session across files by its name and socket.
+func sessionID(node *yaml.Node) string {
+	name := scalarValue(node, "name")
+	if name == nil {
+		return ""
+	}
+	id := name.Value
+	if socket := scalarValue(node, "socket"); socket != nil && socket.Value != "" {
+		id += "@" + socket.Value
+	}
+	return id
+}
+
+// set puts key and value into mapping, merging key by key when both the old
+// and the new value are mappings.
+func (m *merger) set(mapping *yaml.Node, path string, key, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key.Value {
+			continue
+		}
+		existing := mapping.Content[i+1]
+		if existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				m.set(existing, path, value.Content[j], value.Content[j+1])
+			}
+			return
+		}
+		mapping.Content[i], mapping.Content[i+1] = key, value
+		m.sources[key] = source{path: path, line: key.Line}
+		return
+	}
+
+	if value.Kind == yaml.MappingNode {
+		// Copy the mapping so merging into it later leaves the file's own
+		// node, and the sources of its keys, intact.
+		merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		mapping.Content = append(mapping.Content, key, merged)
+		m.sources[key] = source{path: path, line: key.Line}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			m.set(merged, path, value.Content[j], value.Content[j+1])
+		}
+		return
+	}
+	mapping.Content = append(mapping.Content, key, value)
+	m.sources[key] = source{path: path, line: key.Line}
+}
+
+// Show returns the config Load would use for the config file at path, with
+// its includes and overlay merged in, as YAML annotated with the file and
+// line each value comes from.
+func Show(path string) (string, error) {
+	envConfig, err := loadConfigFromEnv()
+	if err != nil {
+		return "", err
+	}
+	profile := resolveProfile(envConfig.Profile)
+	layers, err := readLayers(path, profile)
+	if err != nil {
+		return "", err
+	}
+	if err := decodeLayers(layers); err != nil {
+		return "", err
+	}
+	m := mergeLayers(layers)
+
+	clearFormatting(m.root)
+	for node, src := range m.sources {
+		if node.Kind == yaml.ScalarNode && isKey(m.root, node) {
+			node.LineComment = "from " + src.String()
+		} else {
+			node.HeadComment = "from " + src.String()
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# profile: %s\n", profile)
+	for _, l := range layers {
+		fmt.Fprintf(&b, "# file: %s\n", l.path)
+	}
+	if len(m.root.Content) == 0 {
+		return b.String(), nil
+	}
+	b.WriteString("\n")
+	out, err := yaml.Marshal(m.root)
+	if err != nil {
+		return "", fmt.Errorf("failed to print config: %w", err)
+	}
+	b.Write(out)
+	return b.String(), nil
+}
+
+// isKey reports whether node is a mapping key somewhere under root.
+func isKey(root, node *yaml.Node) bool {
+	switch root.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i] == node || isKey(root.Content[i+1], node) {
+				return true
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range root.Content {
+			if isKey(item, node) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clearFormatting drops the comments and styles of the files, so the merged
+// config prints as plain YAML whatever format each file is in.
+func clearFormatting(node *yaml.Node) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	node.Style = 0
+	for _, child := range node.Content {
+		clearFormatting(child)
+	}
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/griggsjared/tm/internal/session"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadLayers(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		profile string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "no includes",
+			files: map[string]string{"config.yaml": "picker: fzf\n"},
+			want:  []string{"config.yaml"},
+		},
+		{
+			name: "globs in order, each file once",
+			files: map[string]string{
+				"config.yaml":      "include:\n  - conf.d/*.yaml\n  - conf.d/a.yaml\n",
+				"conf.d/b.yaml":    "picker: fzf\n",
+				"conf.d/a.yaml":    "include: [../extra.yml]\n",
+				"extra.yml":        "include: [config.yaml]\n",
+				"conf.d/notes.txt": "not yaml: [\n",
+			},
+			want: []string{"config.yaml", "conf.d/a.yaml", "extra.yml", "conf.d/b.yaml"},
+		},
+		{
+			name:  "glob without matches",
+			files: map[string]string{"config.yaml": "include: [conf.d/*.yaml]\n"},
+			want:  []string{"config.yaml"},
+		},
+		{
+			name: "overlay for the profile comes last",
+			files: map[string]string{
+				"config.yaml":        "include: [work.yaml]\n",
+				"work.yaml":          "picker: fzf\n",
+				"config.laptop.yaml": "include: [laptop-extra.yaml]\n",
+				"laptop-extra.yaml":  "picker: builtin\n",
+				"config.server.yaml": "picker: builtin\n",
+			},
+			profile: "laptop",
+			want:    []string{"config.yaml", "work.yaml", "config.laptop.yaml", "laptop-extra.yaml"},
+		},
+		{
+			name:    "missing overlay",
+			files:   map[string]string{"config.yaml": "picker: fzf\n"},
+			profile: "laptop",
+			want:    []string{"config.yaml"},
+		},
+		{
+			name:    "missing include",
+			files:   map[string]string{"config.yaml": "picker: fzf\ninclude:\n  - work.yaml\n"},
+			wantErr: "line 3: included file",
+		},
+		{
+			name:    "include is not a list",
+			files:   map[string]string{"config.yaml": "include: work.yaml\n"},
+			wantErr: "line 1: cannot unmarshal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, tt.files)
+
+			layers, err := readLayers(filepath.Join(dir, "config.yaml"), tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, l := range layers {
+				rel, _ := filepath.Rel(dir, l.path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("layers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_Includes(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include: [conf.d/*.yaml]
+picker: fzf
+popup:
+  width: 60%
+  border: rounded
+sessions:
+  - name: api
+    dir: /src/api
+    aliases: [a]
+  - name: web
+    dir: /src/web
+  - name: api
+    dir: /src/api-work
+    socket: work
+smart_directories:
+  - ~/src
+`,
+		"conf.d/work.yaml": `sessions:
+  - name: api
+    dir: /work/api
+smart_directories:
+  - dir: ~/work
+    depth: 2
+`,
+		"config.laptop.yaml": `picker: builtin
+popup:
+  width: 90%
+sessions:
+  - name: notes
+    dir: ~/notes
+`,
+	})
+	t.Setenv("TM_PROFILE", "laptop")
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Picker != PickerBuiltin {
+		t.Errorf("Picker = %s, want %s", cfg.Picker, PickerBuiltin)
+	}
+	if want := (Popup{Width: "90%", Height: "80%", Border: "rounded"}); cfg.Popup != want {
+		t.Errorf("Popup = %+v, want %+v", cfg.Popup, want)
+	}
+
+	type entry struct{ name, dir, socket string }
+	var sessions []entry
+	for _, pd := range cfg.PreDefinedSessions {
+		sessions = append(sessions, entry{pd.Name, pd.Dir, pd.Socket})
+	}
+	wantSessions := []entry{
+		{"api", "/work/api", ""},
+		{"web", "/src/web", ""},
+		{"api", "/src/api-work", "work"},
+		{"notes", "~/notes", ""},
+	}
+	if !slices.Equal(sessions, wantSessions) {
+		t.Errorf("sessions = %v, want %v", sessions, wantSessions)
+	}
+	if cfg.PreDefinedSessions[0].Aliases != nil {
+		t.Errorf("expected the included api session to replace aliases, got %v", cfg.PreDefinedSessions[0].Aliases)
+	}
+
+	wantDirs := []session.SmartDirectory{{Dir: "~/src"}, {Dir: "~/work", Depth: 2}}
+	if len(cfg.SmartDirectories) != len(wantDirs) {
+		t.Fatalf("smart directories = %+v, want %+v", cfg.SmartDirectories, wantDirs)
+	}
+	for i, sd := range cfg.SmartDirectories {
+		if sd.Dir != wantDirs[i].Dir || sd.Depth != wantDirs[i].Depth {
+			t.Errorf("smart directory %d = %+v, want %+v", i, sd, wantDirs[i])
+		}
+	}
+}
+
+func TestLoad_InvalidInclude(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": "include: [work.yaml]\n",
+		"work.yaml":   "sessions: nope\n",
+	})
+
+	_, err := Load(filepath.Join(dir, "config.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "work.yaml: yaml: unmarshal errors:\n  line 1") {
+		t.Fatalf("expected the error to name the included file, got %v", err)
+	}
+	if _, err := Show(filepath.Join(dir, "config.yaml")); err == nil {
+		t.Fatal("expected Show to fail as well")
+	}
+}
+
+func TestShow(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": `# my config
+include: [work.yaml]
+picker: fzf # the best
+sessions:
+  - name: api
+    dir: /src/api
+smart_directories:
+  - ~/src
+`,
+		"work.yaml": `picker: builtin
+sessions:
+  - name: web
+    dir: /src/web
+`,
+	})
+	t.Setenv("TM_PROFILE", "laptop")
+	main := filepath.Join(dir, "config.yaml")
+	work := filepath.Join(dir, "work.yaml")
+
+	got, err := Show(main)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `# profile: laptop
+# file: ` + main + `
+# file: ` + work + `
+
+picker: builtin # from ` + work + `:1
+sessions:
+    # from ` + main + `:5
+    - name: api
+      dir: /src/api
+    # from ` + work + `:3
+    - name: web
+      dir: /src/web
+smart_directories:
+    # from ` + main + `:8
+    - ~/src
+`
+	if got != want {
+		t.Errorf("Show() =\n%s\nwant:\n%s", got, want)
+	}
+
+	empty := writeConfigFiles(t, map[string]string{"config.yaml": ""})
+	if got, err := Show(filepath.Join(empty, "config.yaml")); err != nil || strings.Contains(got, "\n\n") {
+		t.Errorf("Show() of an empty config = %q, %v", got, err)
+	}
+}
+
+func TestResolveProfile(t *testing.T) {
+	if got := resolveProfile("laptop"); got != "laptop" {
+		t.Errorf("resolveProfile(laptop) = %s, want laptop", got)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		t.Skip("no host name")
+	}
+	want, _, _ := strings.Cut(host, ".")
+	if got := resolveProfile(""); got != want {
+		t.Errorf("resolveProfile() = %s, want %s", got, want)
+	}
+}
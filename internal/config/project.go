@@ -0,0 +1,59 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/griggsjared/tm/internal/session"
+)
+
+// projectFileNames are the project-local definition files looked for at the
+// root of a smart directory project, in order of preference.
+var projectFileNames = []string{".tm.yaml", ".tmux-session.yaml"}
+
+type projectConfig struct {
+	Aliases []string          `yaml:"aliases"`
+	Windows []windowConfig    `yaml:"windows"`
+	Env     map[string]string `yaml:"env"`
+}
+
+type ProjectLoader struct{}
+
+func NewProjectLoader() *ProjectLoader {
+	return &ProjectLoader{}
+}
+
+func (l *ProjectLoader) Load(dir string) (*session.Project, error) {
+	for _, name := range projectFileNames {
+		path := filepath.Join(dir, name)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("project file is inaccessible: %w", err)
+		}
+
+		var c projectConfig
+		if err := yaml.Unmarshal(content, &c); err != nil {
+			return nil, fmt.Errorf("invalid project file %s: %w", path, err)
+		}
+
+		hash := sha256.Sum256(content)
+		return &session.Project{
+			File: session.ProjectFile{
+				Path: path,
+				Hash: hex.EncodeToString(hash[:]),
+			},
+			Aliases: c.Aliases,
+			Windows: toWindows(c.Windows),
+			Env:     c.Env,
+		}, nil
+	}
+	return nil, nil
+}
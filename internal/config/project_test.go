@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjectLoader_Load(t *testing.T) {
+	content := `
+aliases:
+  - be
+env:
+  AWS_PROFILE: api
+windows:
+  - name: editor
+    panes:
+      - command: nvim .
+      - command: go test ./...
+        enter: false
+`
+
+	t.Run("no project file", func(t *testing.T) {
+		p, err := NewProjectLoader().Load(t.TempDir())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p != nil {
+			t.Errorf("expected nil, got %+v", p)
+		}
+	})
+
+	t.Run("tm yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, ".tm.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		p, err := NewProjectLoader().Load(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p == nil {
+			t.Fatal("expected project, got nil")
+		}
+		if p.File.Path != path {
+			t.Errorf("expected path %s, got %s", path, p.File.Path)
+		}
+		if len(p.File.Hash) != 64 {
+			t.Errorf("expected sha256 hex hash, got %q", p.File.Hash)
+		}
+		if len(p.Aliases) != 1 || p.Aliases[0] != "be" {
+			t.Errorf("unexpected aliases %v", p.Aliases)
+		}
+		if p.Env["AWS_PROFILE"] != "api" {
+			t.Errorf("unexpected env %v", p.Env)
+		}
+		if len(p.Windows) != 1 || len(p.Windows[0].Panes) != 2 || !p.Windows[0].Panes[1].NoEnter {
+			t.Errorf("unexpected windows %+v", p.Windows)
+		}
+	})
+
+	t.Run("tmux-session yaml and hash changes with content", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, ".tmux-session.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		first, err := NewProjectLoader().Load(dir)
+		if err != nil || first == nil {
+			t.Fatalf("expected project, got %v, %v", first, err)
+		}
+
+		if err := os.WriteFile(path, []byte(content+"\n# edited\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		second, err := NewProjectLoader().Load(dir)
+		if err != nil || second == nil {
+			t.Fatalf("expected project, got %v, %v", second, err)
+		}
+		if first.File.Hash == second.File.Hash {
+			t.Error("expected hash to change when the file changes")
+		}
+	})
+
+	t.Run("tm yaml wins", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ".tm.yaml"), []byte("aliases: [a]"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".tmux-session.yaml"), []byte("aliases: [b]"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		p, err := NewProjectLoader().Load(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.Aliases[0] != "a" {
+			t.Errorf("expected .tm.yaml to win, got %v", p.Aliases)
+		}
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ".tm.yaml"), []byte("windows: ["), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewProjectLoader().Load(dir)
+		if err == nil || !strings.Contains(err.Error(), "invalid project file") {
+			t.Errorf("expected invalid project file error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// schema is the part of JSON Schema the config file needs.
+type schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	AnyOf                []*schema          `json:"anyOf,omitempty"`
+}
+
+// schemaDocs describes every key of the config file, by the name of the
+// struct holding it and its yaml key. Schema fails for a key without one, so
+// a new setting can't be added without documenting it.
+var schemaDocs = map[string]string{
+	"fileConfig.include":           "Other config files to merge in, relative to this file. Globs include every matching file in order.",
+	"fileConfig.picker":            "What tm shows to pick a session: fzf when it is installed and the built-in picker otherwise (auto), always fzf, or always the built-in picker.",
+	"fileConfig.match":             "How a query picks a session.",
+	"fileConfig.popup":             "The tmux popup tm popup opens the picker in.",
+	"fileConfig.sessions":          "Pre-defined sessions, available whether they are running or not.",
+	"fileConfig.smart_directories": "Directories whose projects are offered as sessions, as a plain path or a mapping.",
+
+	"matchConfig.strategy": "ranked attaches to the best match, prefix only matches the start of names and aliases.",
+	"matchConfig.dirs":     "Also match the query against each session's directory.",
+
+	"popupConfig.width":  "Width in columns or as a percentage of the terminal. Defaults to 80%.",
+	"popupConfig.height": "Height in lines or as a percentage of the terminal. Defaults to 80%.",
+	"popupConfig.border": "Border style of the popup.",
+	"popupConfig.always": "Open the picker in a popup whenever tm runs inside tmux without a query.",
+
+	"sessionConfig.dir":     "Directory the session starts in.",
+	"sessionConfig.name":    "Session name.",
+	"sessionConfig.aliases": "Other names that open the session.",
+	"sessionConfig.socket":  "The tmux server the session lives on, a socket name or a path containing /.",
+	"sessionConfig.windows": "Windows to create along with the session.",
+	"sessionConfig.env":     "Environment variables for every window of the session.",
+
+	"smartDirectoryConfig.dir":           "Directory to scan for projects.",
+	"smartDirectoryConfig.depth":         "How many levels below dir to scan, at least 1. Defaults to 1.",
+	"smartDirectoryConfig.markers":       "Files or directories that mark a project.",
+	"smartDirectoryConfig.ignore":        "Directories to skip, along with everything below them.",
+	"smartDirectoryConfig.include":       "When set, only projects matching one of these patterns are listed.",
+	"smartDirectoryConfig.show_hidden":   "Scan directories whose names start with a dot.",
+	"smartDirectoryConfig.prefix":        "Text put in front of every session name from this directory.",
+	"smartDirectoryConfig.name_template": "How to build session names from {base}, {parent}, {root} and {path}. Defaults to {base}.",
+	"smartDirectoryConfig.env":           "Environment variables for every window of the sessions.",
+
+	"hooksConfig.on_create": "Commands run before tmux creates the session.",
+	"hooksConfig.on_attach": "Commands run before every attach or switch.",
+	"hooksConfig.on_detach": "Commands run whenever a client detaches from the session.",
+	"hooksConfig.on_kill":   "Commands run before tm kills the session.",
+
+	"hookConfig.command": "Shell command to run.",
+	"hookConfig.abort":   "Stop the attach or kill when the command fails.",
+
+	"windowConfig.name":    "Window name.",
+	"windowConfig.dir":     "Working directory, relative to the session directory.",
+	"windowConfig.layout":  "Any tmux layout, e.g. main-vertical, tiled or a custom layout string.",
+	"windowConfig.command": "Command to run in the first pane, unless that pane sets its own.",
+	"windowConfig.enter":   "Press Enter after typing the command. Defaults to true.",
+	"windowConfig.panes":   "Panes to split the window into.",
+
+	"paneConfig.dir":     "Working directory, relative to the window directory.",
+	"paneConfig.command": "Command to run in the pane.",
+	"paneConfig.enter":   "Press Enter after typing the command. Defaults to true.",
+}
+
+// schemaValues lists the values Load accepts for settings that take one of a
+// few.
+var schemaValues = map[string][]string{
+	"fileConfig.picker":    {PickerAuto, PickerFzf, PickerBuiltin},
+	"matchConfig.strategy": {MatchRanked, MatchPrefix},
+	"popupConfig.border":   popupBorders,
+}
+
+// schemaPatterns holds the patterns of settings Load checks with one.
+var schemaPatterns = map[string]string{
+	"popupConfig.width":  popupSize.String(),
+	"popupConfig.height": popupSize.String(),
+}
+
+var yamlUnmarshaler = reflect.TypeFor[yaml.Unmarshaler]()
+
+// Schema returns a JSON Schema for the config file, generated from the
+// structs Load decodes it into, for editors to complete and validate it.
+func Schema() ([]byte, error) {
+	s, err := schemaFor(reflect.TypeFor[fileConfig]())
+	if err != nil {
+		return nil, err
+	}
+	s.Schema = "http://json-schema.org/draft-07/schema#"
+	s.Title = "tm config"
+
+	out, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write schema: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+func schemaFor(t reflect.Type) (*schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &schema{Type: "boolean"}, nil
+	case reflect.Int:
+		return &schema{Type: "integer"}, nil
+	case reflect.Slice:
+		items, err := schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		values, err := schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		s := &schema{Type: "object", Properties: make(map[string]*schema), AdditionalProperties: false}
+		if err := addProperties(s, t); err != nil {
+			return nil, err
+		}
+		// Types decoding themselves accept a plain string as well, the
+		// short form of their first field.
+		if reflect.PointerTo(t).Implements(yamlUnmarshaler) {
+			return &schema{AnyOf: []*schema{{Type: "string"}, s}}, nil
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("no schema for %s", t)
+}
+
+func addProperties(s *schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if opts == "inline" {
+			if err := addProperties(s, f.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		key := t.Name() + "." + name
+		doc, ok := schemaDocs[key]
+		if !ok {
+			return fmt.Errorf("no schema description for %s", key)
+		}
+		p, err := schemaFor(f.Type)
+		if err != nil {
+			return err
+		}
+		p.Description = doc
+		p.Enum = slices.Clone(schemaValues[key])
+		p.Pattern = schemaPatterns[key]
+		s.Properties[name] = p
+	}
+	return nil
+}
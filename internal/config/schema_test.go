@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	got, err := Schema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want, err := os.ReadFile("../../config.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("config.schema.json is out of date with the config structs, run make schema")
+	}
+
+	var s map[string]any
+	if err := json.Unmarshal(got, &s); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	sessions := s["properties"].(map[string]any)["sessions"].(map[string]any)
+	props := sessions["items"].(map[string]any)["properties"].(map[string]any)
+	for _, key := range []string{"name", "dir", "aliases", "socket", "windows", "env", "on_create", "on_kill"} {
+		if _, ok := props[key]; !ok {
+			t.Errorf("expected sessions to have %q", key)
+		}
+	}
+}
+
+// TestSchema_Docs makes sure descriptions, values and patterns are not kept
+// for keys the config file no longer has.
+func TestSchema_Docs(t *testing.T) {
+	keys := make(map[string]bool)
+	var walk func(reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if opts != "inline" {
+				keys[t.Name()+"."+name] = true
+			}
+			walk(f.Type)
+		}
+	}
+	walk(reflect.TypeFor[fileConfig]())
+
+	for _, m := range []map[string]bool{mapKeys(schemaDocs), mapKeys(schemaValues), mapKeys(schemaPatterns)} {
+		for key := range m {
+			if !keys[key] {
+				t.Errorf("schema entry %s has no matching config key", key)
+			}
+		}
+	}
+}
+
+func mapKeys[V any](m map[string]V) map[string]bool {
+	keys := make(map[string]bool, len(m))
+	for k := range m {
+		keys[k] = true
+	}
+	return keys
+}
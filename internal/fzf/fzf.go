@@ -76,38 +76,109 @@ func (c *Client) Version() string {
 	return strings.TrimSpace(parts[0])
 }
 
-func (c *Client) Select(items []string, query string) (int, bool, error) {
+// Options changes how the picker looks. Preview is a shell command fzf runs
+// for the highlighted item, where {1} is replaced by its 1-based index.
+// Header is shown above the list. Expect lists keys such as ctrl-x that close
+// the picker like enter does and are reported back in Selection.Key.
+type Options struct {
+	Preview string
+	Header  string
+	Expect  []string
+}
+
+// Selection is what the user did in the picker. Key is the Expect key that
+// closed it, empty for enter, and Query is what they typed. Index is -1 when
+// an Expect key was pressed with nothing highlighted.
+type Selection struct {
+	Index int
+	Key   string
+	Query string
+}
+
+func (c *Client) Select(items []string, query string, opts Options) (Selection, bool, error) {
+	indexes, key, typed, ok, err := c.run(items, query, opts)
+	if err != nil || !ok {
+		return Selection{}, false, err
+	}
+	sel := Selection{Index: -1, Key: key, Query: typed}
+	if len(indexes) > 0 {
+		sel.Index = indexes[0]
+	}
+	return sel, true, nil
+}
+
+// SelectMulti lets the user mark several items with tab and returns the
+// indexes of all marked items, or of the current one when none are marked.
+func (c *Client) SelectMulti(items []string, query string, opts Options) ([]int, bool, error) {
+	indexes, _, _, ok, err := c.run(items, query, opts, "--multi")
+	return indexes, ok, err
+}
+
+func (c *Client) run(items []string, query string, opts Options, extraArgs ...string) (indexes []int, key, typed string, ok bool, err error) {
 	if !c.IsAvailable() {
-		return 0, false, fmt.Errorf("fzf is not available")
+		return nil, "", "", false, fmt.Errorf("fzf is not available")
 	}
 
-	args := make([]string, 0, 4)
-	args = append(args, "--exit-0", "--with-nth=2..", fmt.Sprintf("--query=%s", query))
+	expect := len(opts.Expect) > 0
+	args := make([]string, 0, 7+len(extraArgs))
+	if !expect {
+		// With action keys the picker has to stay open on an empty match, so
+		// the query can still be used to create a new session.
+		args = append(args, "--exit-0")
+	}
+	args = append(args, "--with-nth=2..", fmt.Sprintf("--query=%s", query))
+	if opts.Preview != "" {
+		args = append(args, "--preview="+opts.Preview)
+	}
+	if opts.Header != "" {
+		args = append(args, "--header="+opts.Header)
+	}
+	if expect {
+		args = append(args, "--print-query", "--expect="+strings.Join(opts.Expect, ","))
+	}
+	args = append(args, extraArgs...)
 
 	var stdin bytes.Buffer
 	for i, item := range items {
 		fmt.Fprintf(&stdin, "%d\t%s\n", i+1, item)
 	}
 
-	output, exitCode, err := c.runner.Run(c.path, args, &stdin, os.Stderr)
-	if err != nil {
-		if exitCode == 1 || exitCode == 130 {
-			return 0, false, nil
+	output, exitCode, runErr := c.runner.Run(c.path, args, &stdin, os.Stderr)
+	if runErr != nil {
+		// fzf exits with 1 when nothing matched, even when an expect key
+		// closed it, so the output still has to be read in that case.
+		if exitCode == 130 || (exitCode == 1 && !expect) {
+			return nil, "", "", false, nil
+		}
+		if exitCode != 1 {
+			return nil, "", "", false, fmt.Errorf("fzf error: %w", runErr)
 		}
-		return 0, false, fmt.Errorf("fzf error: %w", err)
 	}
 
-	selected := strings.TrimSpace(string(output))
-	if selected == "" {
-		return 0, false, nil
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if expect {
+		if len(lines) < 2 {
+			return nil, "", "", false, nil
+		}
+		typed, key, lines = lines[0], lines[1], lines[2:]
 	}
 
-	parts := strings.SplitN(selected, "\t", 2)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
 
-	idx, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0, false, fmt.Errorf("failed to parse selection index: %w", err)
+		parts := strings.SplitN(line, "\t", 2)
+
+		idx, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, "", "", false, fmt.Errorf("failed to parse selection index: %w", err)
+		}
+		indexes = append(indexes, idx-1)
 	}
 
-	return idx - 1, true, nil
+	if len(indexes) == 0 && key == "" {
+		return nil, "", "", false, nil
+	}
+	return indexes, key, typed, true, nil
 }
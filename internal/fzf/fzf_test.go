@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -305,7 +306,7 @@ func TestClient_Select(t *testing.T) {
 			tr := &TestRunner{output: tt.trOutput, exitCode: tt.trExitCode, error: tt.trError}
 			client := NewClient(tr, tt.path)
 
-			idx, ok, err := client.Select(tt.items, tt.query)
+			sel, ok, err := client.Select(tt.items, tt.query, Options{})
 
 			if tt.wantErr {
 				if err == nil {
@@ -320,8 +321,8 @@ func TestClient_Select(t *testing.T) {
 				}
 			}
 
-			if idx != tt.wantIdx {
-				t.Fatalf("Select() idx = %d, want %d", idx, tt.wantIdx)
+			if sel.Index != tt.wantIdx {
+				t.Fatalf("Select() idx = %d, want %d", sel.Index, tt.wantIdx)
 			}
 			if ok != tt.wantOk {
 				t.Fatalf("Select() ok = %v, want %v", ok, tt.wantOk)
@@ -359,6 +360,90 @@ func TestClient_Select(t *testing.T) {
 	}
 }
 
+func TestClient_SelectMulti(t *testing.T) {
+	tests := []struct {
+		name        string
+		trOutput    []byte
+		trExitCode  int
+		trError     error
+		wantIdxs    []int
+		wantOk      bool
+		errContains string
+	}{
+		{
+			name:     "several marked items",
+			trOutput: []byte("1\talpha\n3\tgamma\n"),
+			wantIdxs: []int{0, 2},
+			wantOk:   true,
+		},
+		{
+			name:     "single item",
+			trOutput: []byte("2\tbeta\n"),
+			wantIdxs: []int{1},
+			wantOk:   true,
+		},
+		{
+			name:       "cancelled",
+			trExitCode: 130,
+			trError:    errors.New("exit status 130"),
+		},
+		{
+			name:        "non-numeric index returns error",
+			trOutput:    []byte("1\talpha\nabc\tbeta\n"),
+			errContains: "failed to parse selection index",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &TestRunner{output: tt.trOutput, exitCode: tt.trExitCode, error: tt.trError}
+			client := NewClient(tr, "/usr/local/bin/fzf")
+
+			idxs, ok, err := client.SelectMulti([]string{"alpha", "beta", "gamma"}, "a", Options{})
+
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.wantOk {
+				t.Fatalf("SelectMulti() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !slices.Equal(idxs, tt.wantIdxs) {
+				t.Fatalf("SelectMulti() idxs = %v, want %v", idxs, tt.wantIdxs)
+			}
+			if !slices.Contains(tr.providedArgs, "--multi") {
+				t.Fatalf("expected --multi in args, got %v", tr.providedArgs)
+			}
+		})
+	}
+}
+
+func TestClient_Select_Preview(t *testing.T) {
+	tr := &TestRunner{output: []byte("1\talpha\n")}
+	client := NewClient(tr, "/usr/local/bin/fzf")
+
+	if _, _, err := client.Select([]string{"alpha"}, "", Options{Preview: "tm preview {1}"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(tr.providedArgs, "--preview=tm preview {1}") {
+		t.Errorf("expected preview arg, got %v", tr.providedArgs)
+	}
+
+	if _, _, err := client.Select([]string{"alpha"}, "", Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, arg := range tr.providedArgs {
+		if strings.HasPrefix(arg, "--preview") {
+			t.Errorf("expected no preview arg without a preview command, got %v", tr.providedArgs)
+		}
+	}
+}
+
 func TestFzfRunner_Run(t *testing.T) {
 	runner := NewRunner()
 
@@ -424,3 +509,73 @@ func TestFzfRunner_Run_StderrPassthrough(t *testing.T) {
 		t.Fatalf("expected stderr to contain %q, got %q", "error", stderr.String())
 	}
 }
+
+func TestClient_Select_Expect(t *testing.T) {
+	tests := []struct {
+		name       string
+		trOutput   []byte
+		trExitCode int
+		trError    error
+		want       Selection
+		wantOk     bool
+	}{
+		{
+			name:     "enter",
+			trOutput: []byte("be\n\n2\tbeta\n"),
+			want:     Selection{Index: 1, Query: "be"},
+			wantOk:   true,
+		},
+		{
+			name:     "action key on an item",
+			trOutput: []byte("be\nctrl-x\n2\tbeta\n"),
+			want:     Selection{Index: 1, Key: "ctrl-x", Query: "be"},
+			wantOk:   true,
+		},
+		{
+			name:       "action key without a match",
+			trOutput:   []byte("zzz\nctrl-n\n"),
+			trExitCode: 1,
+			trError:    errors.New("exit status 1"),
+			want:       Selection{Index: -1, Key: "ctrl-n", Query: "zzz"},
+			wantOk:     true,
+		},
+		{
+			name:       "enter without a match",
+			trOutput:   []byte("zzz\n\n"),
+			trExitCode: 1,
+			trError:    errors.New("exit status 1"),
+		},
+		{
+			name:       "cancelled",
+			trExitCode: 130,
+			trError:    errors.New("exit status 130"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &TestRunner{output: tt.trOutput, exitCode: tt.trExitCode, error: tt.trError}
+			client := NewClient(tr, "/usr/local/bin/fzf")
+
+			sel, ok, err := client.Select([]string{"alpha", "beta"}, "b", Options{Header: "keys", Expect: []string{"ctrl-x", "ctrl-n"}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.wantOk {
+				t.Fatalf("Select() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && sel != tt.want {
+				t.Fatalf("Select() = %+v, want %+v", sel, tt.want)
+			}
+
+			for _, arg := range []string{"--header=keys", "--print-query", "--expect=ctrl-x,ctrl-n"} {
+				if !slices.Contains(tr.providedArgs, arg) {
+					t.Errorf("expected %s in args, got %v", arg, tr.providedArgs)
+				}
+			}
+			if slices.Contains(tr.providedArgs, "--exit-0") {
+				t.Errorf("expected the picker to stay open on an empty match, got %v", tr.providedArgs)
+			}
+		})
+	}
+}
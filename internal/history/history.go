@@ -0,0 +1,204 @@
+package history
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/griggsjared/tm/internal/atomicfile"
+)
+
+// maxTotalCount caps the sum of all counts. Once it is exceeded every count
+// is scaled down and entries that drop below one are forgotten, so sessions
+// that are no longer used age out instead of ranking high forever.
+const maxTotalCount = 1000
+
+// Entry is the attach history of one session. Count is fractional because
+// aging scales it down. Score combines Count with how recently the session
+// was attached and is filled in when entries are read.
+type Entry struct {
+	Name       string    `json:"-"`
+	Dir        string    `json:"dir"`
+	Count      float64   `json:"count"`
+	LastAccess time.Time `json:"last_access"`
+	Score      float64   `json:"-"`
+}
+
+// score weighs the count by the age of the last access, the same buckets
+// zoxide uses.
+func (e Entry) score(now time.Time) float64 {
+	age := now.Sub(e.LastAccess)
+	switch {
+	case age < time.Hour:
+		return e.Count * 4
+	case age < 24*time.Hour:
+		return e.Count * 2
+	case age < 7*24*time.Hour:
+		return e.Count / 2
+	default:
+		return e.Count / 4
+	}
+}
+
+// Store records which sessions are attached to, and how often, keyed by
+// session name.
+type Store struct {
+	path string
+	now  func() time.Time
+}
+
+func NewStore(path string) *Store {
+	return &Store{
+		path: path,
+		now:  time.Now,
+	}
+}
+
+// Record counts an attach to the session name in dir.
+func (s *Store) Record(name, dir string) error {
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	e := entries[name]
+	e.Dir = dir
+	e.Count++
+	e.LastAccess = s.now()
+	entries[name] = e
+
+	age(entries)
+	return s.save(entries)
+}
+
+// Rename moves the history of the session oldName to newName, so a renamed
+// session keeps its rank. Any history newName already had is replaced.
+func (s *Store) Rename(oldName, newName string) error {
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	e, ok := entries[oldName]
+	if !ok {
+		return nil
+	}
+	delete(entries, oldName)
+	entries[newName] = e
+	return s.save(entries)
+}
+
+// Scores returns the frecency score of every session in the history. An
+// unreadable history has no scores, so ranking falls back to the default
+// order.
+func (s *Store) Scores() map[string]float64 {
+	entries, err := s.load()
+	if err != nil {
+		return nil
+	}
+
+	now := s.now()
+	scores := make(map[string]float64, len(entries))
+	for name, e := range entries {
+		scores[name] = e.score(now)
+	}
+	return scores
+}
+
+// Entries returns the history ordered by score, highest first.
+func (s *Store) Entries() ([]Entry, error) {
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s.sorted(entries), nil
+}
+
+// Prune removes the entries remove returns true for and returns them.
+func (s *Store) Prune(remove func(Entry) bool) ([]Entry, error) {
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Entry
+	for _, e := range s.sorted(entries) {
+		if remove(e) {
+			delete(entries, e.Name)
+			removed = append(removed, e)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, s.save(entries)
+}
+
+func (s *Store) sorted(entries map[string]Entry) []Entry {
+	now := s.now()
+	list := make([]Entry, 0, len(entries))
+	for name, e := range entries {
+		e.Name = name
+		e.Score = e.score(now)
+		list = append(list, e)
+	}
+	slices.SortFunc(list, func(a, b Entry) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return list
+}
+
+func age(entries map[string]Entry) {
+	var total float64
+	for _, e := range entries {
+		total += e.Count
+	}
+	if total <= maxTotalCount {
+		return
+	}
+
+	for name, e := range entries {
+		e.Count *= 0.9
+		if e.Count < 1 {
+			delete(entries, name)
+			continue
+		}
+		entries[name] = e
+	}
+}
+
+func (s *Store) load() (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("history is inaccessible: %w", err)
+	}
+	if len(content) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("history is corrupt: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *Store) save(entries map[string]Entry) error {
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return atomicfile.Write(s.path, content, 0600)
+}
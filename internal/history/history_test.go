@@ -0,0 +1,194 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, now *time.Time) *Store {
+	t.Helper()
+	s := NewStore(filepath.Join(t.TempDir(), "state", "tm", "history.json"))
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestStore_RecordAndScores(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(t, &now)
+
+	// "daily" is used often but not in the last week, "fresh" once just now.
+	for range 8 {
+		if err := store.Record("daily", "/src/daily"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	now = now.Add(8 * 24 * time.Hour)
+	if err := store.Record("fresh", "/src/fresh"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	scores := store.Scores()
+	if scores["daily"] != 2 {
+		t.Errorf("daily score = %v, want 2", scores["daily"])
+	}
+	if scores["fresh"] != 4 {
+		t.Errorf("fresh score = %v, want 4", scores["fresh"])
+	}
+	if _, ok := scores["unknown"]; ok {
+		t.Error("expected no score for an unknown session")
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "fresh" || entries[1].Name != "daily" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if entries[1].Count != 8 || entries[1].Dir != "/src/daily" {
+		t.Errorf("unexpected daily entry %+v", entries[1])
+	}
+}
+
+func TestEntry_Score(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		age  time.Duration
+		want float64
+	}{
+		{name: "within the hour", age: 10 * time.Minute, want: 40},
+		{name: "within the day", age: 5 * time.Hour, want: 20},
+		{name: "within the week", age: 3 * 24 * time.Hour, want: 5},
+		{name: "older", age: 30 * 24 * time.Hour, want: 2.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{Count: 10, LastAccess: now.Add(-tt.age)}
+			if got := e.score(now); got != tt.want {
+				t.Errorf("score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_Aging(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(t, &now)
+
+	for range maxTotalCount {
+		if err := store.Record("busy", "/src/busy"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Record("rare", "/src/rare"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "busy" {
+		t.Fatalf("expected rare entry to age out, got %+v", entries)
+	}
+	if entries[0].Count != maxTotalCount*0.9 {
+		t.Errorf("busy count = %v, want %v", entries[0].Count, maxTotalCount*0.9)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(t, &now)
+	for _, name := range []string{"api", "web", "old"} {
+		if err := store.Record(name, "/src/"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := store.Prune(func(e Entry) bool { return e.Name == "old" || e.Dir == "/src/web" })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 2 || removed[0].Name != "old" || removed[1].Name != "web" {
+		t.Errorf("unexpected removed entries %+v", removed)
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "api" {
+		t.Errorf("unexpected remaining entries %+v", entries)
+	}
+
+	removed, err = store.Prune(func(e Entry) bool { return false })
+	if err != nil || removed != nil {
+		t.Errorf("expected nothing removed, got %v, %v", removed, err)
+	}
+}
+
+func TestStore_Rename(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(t, &now)
+	for range 3 {
+		if err := store.Record("web", "/src/web"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Record("frontend", "/src/old-frontend"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Rename("web", "frontend"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "frontend" || entries[0].Count != 3 || entries[0].Dir != "/src/web" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+
+	if err := store.Rename("unknown", "other"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.Scores()["other"]; ok {
+		t.Error("expected no history for a session that had none")
+	}
+}
+
+func TestStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(path)
+
+	if scores := store.Scores(); len(scores) != 0 {
+		t.Errorf("expected no scores, got %v", scores)
+	}
+	if _, err := store.Entries(); err == nil || !strings.Contains(err.Error(), "history is corrupt") {
+		t.Errorf("expected corrupt history error, got %v", err)
+	}
+	if err := store.Record("api", "/src/api"); err == nil {
+		t.Error("expected record to fail on a corrupt history")
+	}
+}
+
+func TestStore_RecordMkdirError(t *testing.T) {
+	blockingFile := filepath.Join(t.TempDir(), "blocking")
+	if err := os.WriteFile(blockingFile, []byte(""), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(filepath.Join(blockingFile, "history.json"))
+	if err := store.Record("api", "/src/api"); err == nil {
+		t.Fatal("expected error")
+	}
+}
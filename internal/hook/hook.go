@@ -0,0 +1,81 @@
+package hook
+
+import (
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/griggsjared/tm/internal/session"
+	"github.com/griggsjared/tm/internal/shell"
+)
+
+type Runner interface {
+	Run(dir string, command string, env []string) error
+}
+
+type ShellRunner struct{}
+
+func NewRunner() *ShellRunner {
+	return &ShellRunner{}
+}
+
+// Run executes command with sh in dir, passing env on top of the current
+// environment. Output goes straight to the terminal so hooks can report
+// progress.
+func (r *ShellRunner) Run(dir string, command string, env []string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+type Client struct {
+	runner Runner
+}
+
+func NewClient(r Runner) *Client {
+	return &Client{
+		runner: r,
+	}
+}
+
+// Run runs a single hook for s. The session name, directory and the event
+// are exposed to the hook as TM_SESSION_NAME, TM_SESSION_DIR and TM_HOOK,
+// alongside the session's own environment variables.
+func (c *Client) Run(event string, h session.Hook, s *session.Session) error {
+	return c.runner.Run(s.Dir, h.Command, env(event, s))
+}
+
+// Script returns a shell command line that runs h for s with the same
+// environment as Run, for hooks that tmux fires after tm has exited.
+func (c *Client) Script(event string, h session.Hook, s *session.Session) string {
+	var b strings.Builder
+	b.WriteString("export")
+	for _, e := range env(event, s) {
+		name, value, _ := strings.Cut(e, "=")
+		b.WriteString(" " + name + "=" + shell.Quote(value))
+	}
+	b.WriteString("; ")
+	if s.Dir != "" {
+		b.WriteString("cd " + shell.Quote(s.Dir) + " || exit 1; ")
+	}
+	b.WriteString(h.Command)
+	return b.String()
+}
+
+func env(event string, s *session.Session) []string {
+	env := []string{
+		"TM_SESSION_NAME=" + s.Name,
+		"TM_SESSION_DIR=" + s.Dir,
+		"TM_HOOK=" + event,
+	}
+	for _, k := range slices.Sorted(maps.Keys(s.Env)) {
+		env = append(env, k+"="+s.Env[k])
+	}
+	return env
+}
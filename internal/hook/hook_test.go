@@ -0,0 +1,138 @@
+package hook
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/griggsjared/tm/internal/session"
+)
+
+type TestRunner struct {
+	error           error
+	providedDir     string
+	providedCommand string
+	providedEnv     []string
+}
+
+func (t *TestRunner) Run(dir string, command string, env []string) error {
+	t.providedDir = dir
+	t.providedCommand = command
+	t.providedEnv = env
+	return t.error
+}
+
+func TestNewRunner(t *testing.T) {
+	runner := NewRunner()
+	if runner == nil {
+		t.Fatalf("Expected a non-nil ShellRunner")
+	}
+}
+
+func TestClient_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		trError error
+		wantErr bool
+	}{
+		{
+			name: "successful hook",
+		},
+		{
+			name:    "failing hook",
+			trError: errors.New("exit status 1"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &TestRunner{error: tt.trError}
+			client := NewClient(tr)
+
+			s := &session.Session{Name: "api", Dir: "/src/api"}
+			err := client.Run("on_create", session.Hook{Command: "direnv allow"}, s)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tr.providedDir != "/src/api" {
+				t.Errorf("expected dir /src/api, got %s", tr.providedDir)
+			}
+			if tr.providedCommand != "direnv allow" {
+				t.Errorf("expected command %q, got %q", "direnv allow", tr.providedCommand)
+			}
+			wantEnv := []string{"TM_SESSION_NAME=api", "TM_SESSION_DIR=/src/api", "TM_HOOK=on_create"}
+			if !slices.Equal(tr.providedEnv, wantEnv) {
+				t.Errorf("expected env %v, got %v", wantEnv, tr.providedEnv)
+			}
+		})
+	}
+}
+
+func TestClient_Script(t *testing.T) {
+	client := NewClient(&TestRunner{})
+
+	t.Run("with directory", func(t *testing.T) {
+		s := &session.Session{Name: "it's", Dir: "/src/api"}
+		got := client.Script("on_detach", session.Hook{Command: "vpn down"}, s)
+		want := `export TM_SESSION_NAME='it'\''s' TM_SESSION_DIR='/src/api' TM_HOOK='on_detach'; cd '/src/api' || exit 1; vpn down`
+		if got != want {
+			t.Errorf("Script() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("with session env", func(t *testing.T) {
+		s := &session.Session{Name: "api", Env: map[string]string{"B": "2", "A": "it's"}}
+		got := client.Script("on_detach", session.Hook{Command: "vpn down"}, s)
+		if !strings.Contains(got, ` TM_HOOK='on_detach' A='it'\''s' B='2';`) {
+			t.Errorf("expected sorted session env in script, got %q", got)
+		}
+	})
+
+	t.Run("without directory", func(t *testing.T) {
+		s := &session.Session{Name: "api"}
+		got := client.Script("on_detach", session.Hook{Command: "vpn down"}, s)
+		if strings.Contains(got, "cd ") {
+			t.Errorf("expected no cd without a directory, got %q", got)
+		}
+	})
+
+	t.Run("runs in sh", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		out := filepath.Join(tmpDir, "out")
+		s := &session.Session{Name: "it's", Dir: tmpDir}
+		script := client.Script("on_detach", session.Hook{Command: `echo "$TM_SESSION_NAME $TM_HOOK $(pwd)" > out`}, s)
+		if err := exec.Command("sh", "-c", script).Run(); err != nil {
+			t.Fatalf("script failed: %v", err)
+		}
+		got, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "it's on_detach " + tmpDir + "\n"; string(got) != want {
+			t.Errorf("script output = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestShellRunner_Run(t *testing.T) {
+	t.Run("passes env and dir", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		err := NewRunner().Run(tmpDir, `test "$(pwd)" = "$EXPECTED_DIR"`, []string{"EXPECTED_DIR=" + tmpDir})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("non-zero exit", func(t *testing.T) {
+		err := NewRunner().Run(t.TempDir(), "exit 3", nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
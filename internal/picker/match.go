@@ -0,0 +1,102 @@
+package picker
+
+import (
+	"unicode"
+)
+
+// Scores in the spirit of fzf: every matched character is worth the same,
+// characters at the start of a word and runs of consecutive characters earn
+// a bonus, and gaps between matched characters cost a little.
+const (
+	scoreMatch       = 16
+	scoreGapStart    = -3
+	scoreGapExtend   = -1
+	bonusBoundary    = 8
+	bonusCamel       = 7
+	bonusConsecutive = 4
+	bonusFirstChar   = 2
+)
+
+// Match reports whether pattern matches text as a case-insensitive
+// subsequence, how well it matches, and the rune positions in text it
+// matched. An empty pattern matches everything with a score of 0.
+func Match(pattern, text string) (score int, positions []int, ok bool) {
+	p := []rune(pattern)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+	t := []rune(text)
+
+	// Find the first place where the whole pattern matches, then walk back
+	// from its end to find the shortest match ending there, as fzf does.
+	pi, end := 0, -1
+	for i, r := range t {
+		if unicode.ToLower(r) == unicode.ToLower(p[pi]) {
+			pi++
+			if pi == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	pi = len(p) - 1
+	start := end
+	for i := end; i >= 0; i-- {
+		if unicode.ToLower(t[i]) == unicode.ToLower(p[pi]) {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	positions = make([]int, 0, len(p))
+	pi = 0
+	prev := -1
+	for i := start; i <= end && pi < len(p); i++ {
+		if unicode.ToLower(t[i]) != unicode.ToLower(p[pi]) {
+			continue
+		}
+
+		s := scoreMatch
+		bonus := charBonus(t, i)
+		if pi == 0 {
+			bonus *= bonusFirstChar
+		}
+		s += bonus
+		if prev >= 0 {
+			if gap := i - prev - 1; gap == 0 {
+				s += bonusConsecutive
+			} else {
+				s += scoreGapStart + scoreGapExtend*(gap-1)
+			}
+		}
+
+		score += s
+		positions = append(positions, i)
+		prev = i
+		pi++
+	}
+	return score, positions, true
+}
+
+// charBonus rewards characters that start a word: the first character, one
+// after a separator, or an upper case letter after a lower case one.
+func charBonus(t []rune, i int) int {
+	if i == 0 {
+		return bonusBoundary
+	}
+	prev, cur := t[i-1], t[i]
+	switch {
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return bonusCamel
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package picker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern       string
+		text          string
+		wantOk        bool
+		wantPositions []int
+	}{
+		{name: "empty pattern", pattern: "", text: "api", wantOk: true},
+		{name: "prefix", pattern: "ap", text: "api", wantOk: true, wantPositions: []int{0, 1}},
+		{name: "subsequence across words", pattern: "bap", text: "billing-api", wantOk: true, wantPositions: []int{0, 8, 9}},
+		{name: "case insensitive", pattern: "API", text: "billing-api", wantOk: true, wantPositions: []int{8, 9, 10}},
+		{name: "shortest match", pattern: "ab", text: "a-a-ab", wantOk: true, wantPositions: []int{4, 5}},
+		{name: "unicode", pattern: "ÜÉ", text: "über-café", wantOk: true, wantPositions: []int{0, 8}},
+		{name: "out of order", pattern: "pa", text: "api", wantOk: false},
+		{name: "missing character", pattern: "apx", text: "api", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, positions, ok := Match(tt.pattern, tt.text)
+			if ok != tt.wantOk {
+				t.Fatalf("Match(%q, %q) ok = %v, want %v", tt.pattern, tt.text, ok, tt.wantOk)
+			}
+			if !slices.Equal(positions, tt.wantPositions) {
+				t.Errorf("Match(%q, %q) positions = %v, want %v", tt.pattern, tt.text, positions, tt.wantPositions)
+			}
+		})
+	}
+}
+
+func TestMatch_Ranking(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		better  string
+		worse   string
+	}{
+		{name: "prefix beats middle of a word", pattern: "api", better: "api [/src/api]", worse: "rapid [/src/rapid]"},
+		{name: "word start beats middle of a word", pattern: "api", better: "billing-api", worse: "rapid"},
+		{name: "consecutive beats scattered", pattern: "web", better: "webapp", worse: "wxexbx"},
+		{name: "camel case counts as a word start", pattern: "sm", better: "StateMachine", worse: "plasma"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			better, _, ok := Match(tt.pattern, tt.better)
+			if !ok {
+				t.Fatalf("expected %q to match %q", tt.pattern, tt.better)
+			}
+			worse, _, ok := Match(tt.pattern, tt.worse)
+			if !ok {
+				t.Fatalf("expected %q to match %q", tt.pattern, tt.worse)
+			}
+			if better <= worse {
+				t.Errorf("score(%q) = %d, want more than score(%q) = %d", tt.better, better, tt.worse, worse)
+			}
+		})
+	}
+}
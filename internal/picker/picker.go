@@ -0,0 +1,415 @@
+// Package picker is a small fuzzy finder drawn on the terminal, used to pick
+// sessions when fzf is not installed.
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"github.com/griggsjared/tm/internal/fzf"
+)
+
+// Terminal is what the picker reads keys from and draws on. Close restores
+// the terminal to how it was before Open.
+type Terminal interface {
+	io.ReadWriter
+	Size() (width, height int)
+	Close() error
+}
+
+type Runner interface {
+	Open() (Terminal, error)
+}
+
+type TTYRunner struct{}
+
+func NewRunner() *TTYRunner {
+	return &TTYRunner{}
+}
+
+// Open puts the controlling terminal in raw mode, so the picker gets every
+// key press as it happens.
+func (r *TTYRunner) Open() (Terminal, error) {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open terminal: %w", err)
+	}
+	state, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	return &tty{File: f, state: state}, nil
+}
+
+type tty struct {
+	*os.File
+	state *term.State
+}
+
+func (t *tty) Size() (int, int) {
+	w, h, err := term.GetSize(int(t.Fd()))
+	if err != nil {
+		return 80, 24
+	}
+	return w, h
+}
+
+func (t *tty) Close() error {
+	term.Restore(int(t.Fd()), t.state)
+	return t.File.Close()
+}
+
+type Client struct {
+	runner Runner
+}
+
+// NewClient creates a picker that draws on the terminals r opens. It takes
+// the same options and returns the same selections as the fzf client, apart
+// from previews, which it does not show.
+func NewClient(r Runner) *Client {
+	return &Client{
+		runner: r,
+	}
+}
+
+func (c *Client) IsAvailable() bool {
+	return true
+}
+
+func (c *Client) Select(items []string, query string, opts fzf.Options) (fzf.Selection, bool, error) {
+	s, ok, err := c.run(items, query, opts, false)
+	if err != nil || !ok {
+		return fzf.Selection{}, false, err
+	}
+	sel := fzf.Selection{Index: -1, Key: s.key, Query: string(s.query)}
+	if m, ok := s.current(); ok {
+		sel.Index = m.index
+	}
+	if sel.Index < 0 && sel.Key == "" {
+		return fzf.Selection{}, false, nil
+	}
+	return sel, true, nil
+}
+
+// SelectMulti lets the user mark several items with tab and returns the
+// indexes of all marked items, or of the current one when none are marked.
+func (c *Client) SelectMulti(items []string, query string, opts fzf.Options) ([]int, bool, error) {
+	s, ok, err := c.run(items, query, opts, true)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if len(s.marked) > 0 {
+		indexes := make([]int, 0, len(s.marked))
+		for i := range s.items {
+			if s.marked[i] {
+				indexes = append(indexes, i)
+			}
+		}
+		return indexes, true, nil
+	}
+	if m, ok := s.current(); ok {
+		return []int{m.index}, true, nil
+	}
+	return nil, false, nil
+}
+
+func (c *Client) run(items []string, query string, opts fzf.Options, multi bool) (*state, bool, error) {
+	if len(items) == 0 {
+		return nil, false, nil
+	}
+
+	t, err := c.runner.Open()
+	if err != nil {
+		return nil, false, err
+	}
+	defer t.Close()
+
+	s := newState(items, query, opts, multi)
+
+	io.WriteString(t, enterAltScreen)
+	defer io.WriteString(t, leaveAltScreen)
+
+	buf := make([]byte, 256)
+	for {
+		width, height := t.Size()
+		io.WriteString(t, s.render(width, height))
+
+		n, err := t.Read(buf)
+		if n > 0 {
+			switch s.handle(buf[:n]) {
+			case actionAccept:
+				return s, true, nil
+			case actionCancel:
+				return nil, false, nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read from terminal: %w", err)
+		}
+	}
+}
+
+const (
+	enterAltScreen = "\x1b[?1049h"
+	leaveAltScreen = "\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+	highlight      = "\x1b[1;32m"
+	bold           = "\x1b[1m"
+	dim            = "\x1b[2m"
+	reset          = "\x1b[0m"
+)
+
+type action int
+
+const (
+	actionNone action = iota
+	actionAccept
+	actionCancel
+)
+
+type match struct {
+	index     int
+	score     int
+	positions []int
+}
+
+// state is the picker between key presses: what was typed, which items
+// match it and where the cursor is.
+type state struct {
+	items   []string
+	header  string
+	expect  map[byte]string
+	multi   bool
+	query   []rune
+	matches []match
+	cursor  int
+	offset  int
+	marked  map[int]bool
+	key     string
+}
+
+func newState(items []string, query string, opts fzf.Options, multi bool) *state {
+	s := &state{
+		items:  items,
+		header: opts.Header,
+		expect: make(map[byte]string),
+		multi:  multi,
+		query:  []rune(query),
+		marked: make(map[int]bool),
+	}
+	for _, k := range opts.Expect {
+		if b, ok := ctrlKey(k); ok {
+			s.expect[b] = k
+		}
+	}
+	s.filter()
+	return s
+}
+
+// ctrlKey returns the byte a terminal sends for keys like ctrl-x.
+func ctrlKey(name string) (byte, bool) {
+	letter, ok := strings.CutPrefix(name, "ctrl-")
+	if !ok || len(letter) != 1 || letter[0] < 'a' || letter[0] > 'z' {
+		return 0, false
+	}
+	return letter[0] - 'a' + 1, true
+}
+
+// filter matches the items against the query, best first. Items that score
+// the same keep their order, so the ranking tm gives them still counts.
+func (s *state) filter() {
+	s.matches = s.matches[:0]
+	for i, item := range s.items {
+		if score, positions, ok := Match(string(s.query), item); ok {
+			s.matches = append(s.matches, match{index: i, score: score, positions: positions})
+		}
+	}
+	slices.SortStableFunc(s.matches, func(a, b match) int {
+		return b.score - a.score
+	})
+	s.cursor, s.offset = 0, 0
+}
+
+func (s *state) current() (match, bool) {
+	if s.cursor >= len(s.matches) {
+		return match{}, false
+	}
+	return s.matches[s.cursor], true
+}
+
+func (s *state) move(delta int) {
+	s.cursor = max(0, min(len(s.matches)-1, s.cursor+delta))
+}
+
+// handle applies the keys in one read from the terminal.
+func (s *state) handle(input []byte) action {
+	for len(input) > 0 {
+		b := input[0]
+		if key, ok := s.expect[b]; ok {
+			s.key = key
+			return actionAccept
+		}
+
+		switch b {
+		case '\r':
+			return actionAccept
+		case 0x03, 0x07: // ctrl-c, ctrl-g
+			return actionCancel
+		case 0x1b:
+			if len(input) == 1 {
+				return actionCancel
+			}
+			input = s.escape(input)
+			continue
+		case 0x7f, 0x08: // backspace
+			if len(s.query) > 0 {
+				s.query = s.query[:len(s.query)-1]
+				s.filter()
+			}
+		case 0x15: // ctrl-u
+			s.query = s.query[:0]
+			s.filter()
+		case 0x17: // ctrl-w
+			q := strings.TrimRight(string(s.query), " ")
+			s.query = []rune(q[:strings.LastIndex(q, " ")+1])
+			s.filter()
+		case 0x0e, 0x0a: // ctrl-n, ctrl-j
+			s.move(1)
+		case 0x10, 0x0b: // ctrl-p, ctrl-k
+			s.move(-1)
+		case '\t':
+			if m, ok := s.current(); ok && s.multi {
+				s.marked[m.index] = !s.marked[m.index]
+				if !s.marked[m.index] {
+					delete(s.marked, m.index)
+				}
+				s.move(1)
+			}
+		default:
+			r, size := utf8.DecodeRune(input)
+			if r >= ' ' && r != utf8.RuneError {
+				s.query = append(s.query, r)
+				s.filter()
+			}
+			input = input[size:]
+			continue
+		}
+		input = input[1:]
+	}
+	return actionNone
+}
+
+// escape handles an escape sequence at the start of input, moving the
+// cursor for the arrow keys and ignoring the rest, and returns what follows
+// it.
+func (s *state) escape(input []byte) []byte {
+	if input[1] != '[' && input[1] != 'O' {
+		return input[2:] // alt and a key
+	}
+	for i := 2; i < len(input); i++ {
+		if input[i] < 0x40 || input[i] > 0x7e {
+			continue
+		}
+		switch input[i] {
+		case 'A':
+			s.move(-1)
+		case 'B':
+			s.move(1)
+		}
+		return input[i+1:]
+	}
+	return nil
+}
+
+// render draws the prompt, the number of matches, the header and as many
+// matches as fit below them, then puts the cursor back on the prompt.
+func (s *state) render(width, height int) string {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+
+	prompt := "> " + string(s.query)
+	b.WriteString(truncate(prompt, width))
+	info := fmt.Sprintf("  %d/%d", len(s.matches), len(s.items))
+	if len(s.marked) > 0 {
+		info += fmt.Sprintf(" (%d)", len(s.marked))
+	}
+	b.WriteString("\r\n" + dim + truncate(info, width) + reset)
+
+	rows := height - 2
+	if s.header != "" {
+		b.WriteString("\r\n" + dim + truncate(s.header, width) + reset)
+		rows--
+	}
+	rows = max(rows, 1)
+
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+rows {
+		s.offset = s.cursor - rows + 1
+	}
+
+	for i := s.offset; i < len(s.matches) && i < s.offset+rows; i++ {
+		m := s.matches[i]
+		b.WriteString("\r\n")
+		b.WriteString(s.line(m, i == s.cursor, width))
+	}
+
+	fmt.Fprintf(&b, "\x1b[1;%dH", min(utf8.RuneCountInString(prompt), width-1)+1)
+	return b.String()
+}
+
+// line is one match with its matched characters highlighted, the current
+// one in bold behind a pointer.
+func (s *state) line(m match, current bool, width int) string {
+	var b strings.Builder
+	style := ""
+	if current {
+		style = bold
+		b.WriteString(bold + ">")
+	} else {
+		b.WriteString(" ")
+	}
+	if s.marked[m.index] {
+		b.WriteString("*")
+	} else {
+		b.WriteString(" ")
+	}
+
+	text := []rune(s.items[m.index])
+	if len(text) > width-2 {
+		text = text[:max(width-2, 0)]
+	}
+	matched := make(map[int]bool, len(m.positions))
+	for _, p := range m.positions {
+		matched[p] = true
+	}
+	for i, r := range text {
+		if matched[i] {
+			b.WriteString(highlight + string(r) + reset + style)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(reset)
+	return b.String()
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:max(width, 0)])
+	}
+	return s
+}
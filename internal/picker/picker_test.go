@@ -0,0 +1,261 @@
+package picker
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/griggsjared/tm/internal/fzf"
+)
+
+type TestTerminal struct {
+	input  []string
+	output bytes.Buffer
+	err    error
+	closed bool
+}
+
+func (t *TestTerminal) Read(p []byte) (int, error) {
+	if len(t.input) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, t.input[0])
+	t.input = t.input[1:]
+	return n, nil
+}
+
+func (t *TestTerminal) Write(p []byte) (int, error) {
+	return t.output.Write(p)
+}
+
+func (t *TestTerminal) Size() (int, int) {
+	return 40, 6
+}
+
+func (t *TestTerminal) Close() error {
+	t.closed = true
+	return nil
+}
+
+type TestRunner struct {
+	terminal *TestTerminal
+	err      error
+}
+
+func (r *TestRunner) Open() (Terminal, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.terminal, nil
+}
+
+var items = []string{"api [/src/api]", "web [/src/web]", "billing-api [/src/billing]", "docs [/src/docs]"}
+
+func TestNewRunner(t *testing.T) {
+	if NewRunner() == nil {
+		t.Fatal("expected a non-nil runner")
+	}
+}
+
+func TestClient_Select(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		input   []string
+		opts    fzf.Options
+		want    fzf.Selection
+		wantOk  bool
+		wantErr string
+	}{
+		{
+			name:   "enter picks the first item",
+			input:  []string{"\r"},
+			want:   fzf.Selection{Index: 0},
+			wantOk: true,
+		},
+		{
+			name:   "typing filters",
+			input:  []string{"w", "e", "\r"},
+			want:   fzf.Selection{Index: 1, Query: "we"},
+			wantOk: true,
+		},
+		{
+			name:   "initial query",
+			query:  "doc",
+			input:  []string{"\r"},
+			want:   fzf.Selection{Index: 3, Query: "doc"},
+			wantOk: true,
+		},
+		{
+			name:   "best match first",
+			input:  []string{"bap\r"},
+			want:   fzf.Selection{Index: 2, Query: "bap"},
+			wantOk: true,
+		},
+		{
+			name:   "arrow keys move",
+			input:  []string{"\x1b[B", "\x1b[B", "\x1b[A", "\r"},
+			want:   fzf.Selection{Index: 1},
+			wantOk: true,
+		},
+		{
+			name:   "cursor stops at the last match",
+			input:  []string{"\x0e\x0e\x0e\x0e\x0e\x0e\r"},
+			want:   fzf.Selection{Index: 3},
+			wantOk: true,
+		},
+		{
+			name:   "backspace and ctrl-u edit the query",
+			input:  []string{"xx", "\x7f\x7f", "zz\x15", "we\r"},
+			want:   fzf.Selection{Index: 1, Query: "we"},
+			wantOk: true,
+		},
+		{
+			name:  "enter with no match",
+			input: []string{"zzz", "\r"},
+		},
+		{
+			name:  "escape cancels",
+			input: []string{"\x1b"},
+		},
+		{
+			name:  "ctrl-c cancels",
+			input: []string{"\x03"},
+		},
+		{
+			name: "closed terminal cancels",
+		},
+		{
+			name:   "expect key on an item",
+			input:  []string{"web", "\x18"},
+			opts:   fzf.Options{Expect: []string{"ctrl-x", "ctrl-n"}},
+			want:   fzf.Selection{Index: 1, Key: "ctrl-x", Query: "web"},
+			wantOk: true,
+		},
+		{
+			name:   "expect key without a match",
+			input:  []string{"scratch", "\x0e"},
+			opts:   fzf.Options{Expect: []string{"ctrl-x", "ctrl-n"}},
+			want:   fzf.Selection{Index: -1, Key: "ctrl-n", Query: "scratch"},
+			wantOk: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terminal := &TestTerminal{input: tt.input}
+			client := NewClient(&TestRunner{terminal: terminal})
+
+			sel, ok, err := client.Select(items, tt.query, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.wantOk {
+				t.Fatalf("Select() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && sel != tt.want {
+				t.Errorf("Select() = %+v, want %+v", sel, tt.want)
+			}
+			if !terminal.closed {
+				t.Error("expected the terminal to be restored")
+			}
+			out := terminal.output.String()
+			if !strings.HasPrefix(out, enterAltScreen) || !strings.HasSuffix(out, leaveAltScreen) {
+				t.Errorf("expected the picker to use the alternate screen, got %q", out)
+			}
+		})
+	}
+}
+
+func TestClient_Select_Errors(t *testing.T) {
+	t.Run("no terminal", func(t *testing.T) {
+		client := NewClient(&TestRunner{err: errors.New("failed to open terminal")})
+		if _, _, err := client.Select(items, "", fzf.Options{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		terminal := &TestTerminal{err: errors.New("input/output error")}
+		_, _, err := NewClient(&TestRunner{terminal: terminal}).Select(items, "", fzf.Options{})
+		if err == nil || !strings.Contains(err.Error(), "failed to read from terminal") {
+			t.Fatalf("expected read error, got %v", err)
+		}
+	})
+
+	t.Run("no items", func(t *testing.T) {
+		runner := &TestRunner{err: errors.New("should not open")}
+		if _, ok, err := NewClient(runner).Select(nil, "", fzf.Options{}); ok || err != nil {
+			t.Fatalf("Select() = %v, %v, want not ok", ok, err)
+		}
+	})
+}
+
+func TestClient_SelectMulti(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		wantIdxs []int
+		wantOk   bool
+	}{
+		{name: "tab marks several", input: []string{"\t", "\t", "\r"}, wantIdxs: []int{0, 1}, wantOk: true},
+		{name: "tab again unmarks", input: []string{"\t\x1b[A\t\r"}, wantIdxs: []int{1}, wantOk: true},
+		{name: "current item without marks", input: []string{"\x1b[B\r"}, wantIdxs: []int{1}, wantOk: true},
+		{name: "marks survive filtering", input: []string{"\t", "doc", "\t\r"}, wantIdxs: []int{0, 3}, wantOk: true},
+		{name: "cancelled", input: []string{"\t\x1b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(&TestRunner{terminal: &TestTerminal{input: tt.input}})
+
+			idxs, ok, err := client.SelectMulti(items, "", fzf.Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok != tt.wantOk {
+				t.Fatalf("SelectMulti() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !slices.Equal(idxs, tt.wantIdxs) {
+				t.Errorf("SelectMulti() = %v, want %v", idxs, tt.wantIdxs)
+			}
+		})
+	}
+}
+
+func TestState_Render(t *testing.T) {
+	s := newState(items, "api", fzf.Options{Header: "enter: open"}, false)
+	out := s.render(40, 5)
+
+	for _, want := range []string{
+		"> api",
+		"  2/4",
+		"enter: open",
+		bold + ">" + " " + highlight + "a" + reset + bold,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected render to contain %q, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "web") {
+		t.Errorf("expected items that do not match to be hidden, got %q", out)
+	}
+	if !strings.HasSuffix(out, "\x1b[1;6H") {
+		t.Errorf("expected the cursor after the query, got %q", out)
+	}
+
+	t.Run("scrolls to the cursor", func(t *testing.T) {
+		s := newState(items, "", fzf.Options{}, false)
+		s.move(3)
+		out := s.render(40, 4)
+		if strings.Contains(out, "\r\n  api [") || !strings.Contains(out, "docs") {
+			t.Errorf("expected the list to scroll to the last item, got %q", out)
+		}
+	})
+}
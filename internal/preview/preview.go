@@ -0,0 +1,158 @@
+package preview
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// readmeLines is how much of a README the preview shows.
+const readmeLines = 20
+
+// statusLines caps the git status so a dirty checkout does not push the
+// directory listing out of view.
+const statusLines = 10
+
+type Runner interface {
+	Output(path string, args []string) ([]byte, error)
+}
+
+type CommandRunner struct{}
+
+func NewRunner() *CommandRunner {
+	return &CommandRunner{}
+}
+
+func (r *CommandRunner) Output(path string, args []string) ([]byte, error) {
+	return exec.Command(path, args...).Output()
+}
+
+// Client previews project directories. The tool paths are optional: git
+// adds the branch and status, eza replaces the built-in directory listing
+// and bat highlights the README.
+type Client struct {
+	runner  Runner
+	gitPath string
+	ezaPath string
+	batPath string
+}
+
+func NewClient(r Runner, gitPath, ezaPath, batPath string) *Client {
+	return &Client{
+		runner:  r,
+		gitPath: gitPath,
+		ezaPath: ezaPath,
+		batPath: batPath,
+	}
+}
+
+// Project writes a preview of the project in dir to w: its git branch and
+// status, its entries and the head of its README.
+func (c *Client) Project(w io.Writer, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read project directory: %w", err)
+	}
+
+	fmt.Fprintln(w, dir)
+
+	if status := c.gitStatus(dir); status != "" {
+		fmt.Fprintln(w)
+		fmt.Fprint(w, status)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprint(w, c.listing(dir, entries))
+
+	if readme := findReadme(entries); readme != "" {
+		fmt.Fprintln(w)
+		fmt.Fprint(w, c.readme(filepath.Join(dir, readme)))
+	}
+	return nil
+}
+
+// gitStatus returns the branch line and changed files of the repository at
+// dir, or an empty string when dir is not one or git is missing.
+func (c *Client) gitStatus(dir string) string {
+	if c.gitPath == "" {
+		return ""
+	}
+	output, err := c.runner.Output(c.gitPath, []string{"-C", dir, "status", "--short", "--branch"})
+	if err != nil {
+		return ""
+	}
+	return headLines(string(output), statusLines+1)
+}
+
+func (c *Client) listing(dir string, entries []os.DirEntry) string {
+	if c.ezaPath != "" {
+		output, err := c.runner.Output(c.ezaPath, []string{"-1", "--group-directories-first", "--color=always", dir})
+		if err == nil {
+			return string(output)
+		}
+	}
+
+	var dirs, files []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if e.IsDir() {
+			dirs = append(dirs, e.Name()+"/")
+		} else {
+			files = append(files, e.Name())
+		}
+	}
+
+	var b strings.Builder
+	for _, name := range slices.Concat(dirs, files) {
+		b.WriteString(name + "\n")
+	}
+	return b.String()
+}
+
+func (c *Client) readme(path string) string {
+	if c.batPath != "" {
+		output, err := c.runner.Output(c.batPath, []string{"--color=always", "--style=plain", fmt.Sprintf("--line-range=:%d", readmeLines), path})
+		if err == nil {
+			return string(output)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < readmeLines && scanner.Scan(); i++ {
+		b.WriteString(scanner.Text() + "\n")
+	}
+	return b.String()
+}
+
+// findReadme returns the name of the first README file in entries, matching
+// any case and extension.
+func findReadme(entries []os.DirEntry) string {
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(strings.ToLower(e.Name()), "readme") {
+			return e.Name()
+		}
+	}
+	return ""
+}
+
+func headLines(s string, n int) string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "")
+}
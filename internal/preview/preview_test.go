@@ -0,0 +1,126 @@
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type TestRunner struct {
+	outputs map[string][]byte
+	errors  map[string]error
+	calls   []string
+}
+
+func (t *TestRunner) Output(path string, args []string) ([]byte, error) {
+	t.calls = append(t.calls, path+" "+strings.Join(args, " "))
+	return t.outputs[path], t.errors[path]
+}
+
+func newProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "cmd"), 0755)
+	os.Mkdir(filepath.Join(dir, ".git"), 0755)
+	os.WriteFile(filepath.Join(dir, "go.mod"), nil, 0644)
+	var readme strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&readme, "line %d\n", i)
+	}
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte(readme.String()), 0644)
+	return dir
+}
+
+func TestNewRunner(t *testing.T) {
+	if NewRunner() == nil {
+		t.Fatal("expected a non-nil runner")
+	}
+}
+
+func TestClient_Project(t *testing.T) {
+	t.Run("without tools", func(t *testing.T) {
+		dir := newProject(t)
+		runner := &TestRunner{}
+		var buf bytes.Buffer
+
+		if err := NewClient(runner, "", "", "").Project(&buf, dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := buf.String()
+		if !strings.HasPrefix(got, dir+"\n\ncmd/\nREADME.md\ngo.mod\n\nline 1\n") {
+			t.Errorf("unexpected preview:\n%s", got)
+		}
+		if !strings.Contains(got, "line 20\n") || strings.Contains(got, "line 21") {
+			t.Errorf("expected the first 20 README lines, got:\n%s", got)
+		}
+		if strings.Contains(got, ".git") {
+			t.Errorf("expected hidden entries to be left out, got:\n%s", got)
+		}
+		if len(runner.calls) != 0 {
+			t.Errorf("expected no commands to run, got %v", runner.calls)
+		}
+	})
+
+	t.Run("with git, eza and bat", func(t *testing.T) {
+		dir := newProject(t)
+		var status strings.Builder
+		status.WriteString("## main...origin/main\n")
+		for i := 1; i <= 15; i++ {
+			fmt.Fprintf(&status, " M file%d.go\n", i)
+		}
+		runner := &TestRunner{outputs: map[string][]byte{
+			"/bin/git": []byte(status.String()),
+			"/bin/eza": []byte("cmd\ngo.mod\n"),
+			"/bin/bat": []byte("# highlighted\n"),
+		}}
+		var buf bytes.Buffer
+
+		if err := NewClient(runner, "/bin/git", "/bin/eza", "/bin/bat").Project(&buf, dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := buf.String()
+		for _, want := range []string{"## main...origin/main\n", " M file10.go\n", "\ncmd\ngo.mod\n", "# highlighted\n"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected preview to contain %q, got:\n%s", want, got)
+			}
+		}
+		if strings.Contains(got, "file11.go") {
+			t.Errorf("expected git status to be capped, got:\n%s", got)
+		}
+		wantCalls := []string{
+			"/bin/git -C " + dir + " status --short --branch",
+			"/bin/eza -1 --group-directories-first --color=always " + dir,
+			"/bin/bat --color=always --style=plain --line-range=:20 " + filepath.Join(dir, "README.md"),
+		}
+		if strings.Join(runner.calls, "\n") != strings.Join(wantCalls, "\n") {
+			t.Errorf("unexpected calls:\n%s\nwant:\n%s", strings.Join(runner.calls, "\n"), strings.Join(wantCalls, "\n"))
+		}
+	})
+
+	t.Run("failing tools fall back", func(t *testing.T) {
+		dir := newProject(t)
+		fail := errors.New("exit status 128")
+		runner := &TestRunner{errors: map[string]error{"/bin/git": fail, "/bin/eza": fail, "/bin/bat": fail}}
+		var buf bytes.Buffer
+
+		if err := NewClient(runner, "/bin/git", "/bin/eza", "/bin/bat").Project(&buf, dir); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(buf.String(), dir+"\n\ncmd/\nREADME.md\ngo.mod\n\nline 1\n") {
+			t.Errorf("unexpected preview:\n%s", buf.String())
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewClient(&TestRunner{}, "", "", "").Project(&buf, filepath.Join(t.TempDir(), "gone")); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// defaultNameTemplate names a smart directory session after its directory.
+const defaultNameTemplate = "{base}"
+
+// nameReplacer swaps the characters tmux does not allow in session names.
+// tmux silently renames a session created with them, so the session would
+// never be found again under the name tm asked for.
+var nameReplacer = strings.NewReplacer(".", "_", ":", "_")
+
+// sanitizeName returns name with the characters tmux rejects replaced.
+func sanitizeName(name string) string {
+	return nameReplacer.Replace(name)
+}
+
+// smartProject is a project found in a smart directory along with the
+// session name it is listed and found by.
+type smartProject struct {
+	sd   SmartDirectory
+	root string
+	dir  string
+	name string
+}
+
+// sessionName names the project at dir below root. The NameTemplate
+// placeholders are {base} for the project directory name, {parent} for the
+// name of the directory above it, {root} for the smart directory name and
+// {path} for the path relative to root. Prefix is prepended to the result.
+func (sd SmartDirectory) sessionName(root, dir string) string {
+	template := sd.NameTemplate
+	if template == "" {
+		template = defaultNameTemplate
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		rel = filepath.Base(dir)
+	}
+
+	r := strings.NewReplacer(
+		"{base}", filepath.Base(dir),
+		"{parent}", filepath.Base(filepath.Dir(dir)),
+		"{root}", filepath.Base(root),
+		"{path}", filepath.ToSlash(rel),
+	)
+	return sanitizeName(sd.Prefix + r.Replace(template))
+}
+
+// smartProjects returns every project in the smart directories with a
+// unique session name. The first error expanding a smart directory path is
+// returned alongside the projects of the others.
+func (f *Finder) smartProjects() ([]smartProject, error) {
+	var projects []smartProject
+	var firstErr error
+	for _, sd := range f.smartDirectories {
+		root, err := expandHomeDir(sd.Dir)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		for _, dir := range scanProjects(root, sd) {
+			projects = append(projects, smartProject{
+				sd:   sd,
+				root: root,
+				dir:  dir,
+				name: sd.sessionName(root, dir),
+			})
+		}
+	}
+
+	f.disambiguate(projects)
+	return projects, firstErr
+}
+
+// nameSeparator joins a project name and what tells it apart from others of
+// the same name. @ already marks the tmux server of a session in lists.
+const nameSeparator = "~"
+
+// disambiguate renames projects whose session name is shared with another
+// project, or with a pre-defined session for a different directory, until
+// every name is unique. It first appends the smart directory name, e.g.
+// api~work and api~projects, then the parent directory name for nested
+// projects in the same smart directory, e.g. api~acme, and numbers whatever
+// is still shared, e.g. api~work~2.
+func (f *Finder) disambiguate(projects []smartProject) {
+	reserved := make(map[string]string, len(f.preDefinedSessions))
+	for _, pd := range f.preDefinedSessions {
+		dir, err := expandHomeDir(pd.Dir)
+		if err != nil {
+			continue
+		}
+		reserved[pd.Name] = filepath.Clean(dir)
+	}
+
+	conflicts := func(names map[string]int, p smartProject) bool {
+		dir, isReserved := reserved[p.name]
+		return names[p.name] > 1 || (isReserved && dir != p.dir)
+	}
+
+	suffixes := []func(p smartProject) string{
+		func(p smartProject) string { return filepath.Base(p.root) },
+		func(p smartProject) string { return filepath.Base(filepath.Dir(p.dir)) },
+	}
+	for _, suffix := range suffixes {
+		names := countNames(projects)
+		for i, p := range projects {
+			if conflicts(names, p) {
+				base := p.sd.sessionName(p.root, p.dir)
+				projects[i].name = sanitizeName(base + nameSeparator + suffix(p))
+			}
+		}
+	}
+
+	names := countNames(projects)
+	kept := make(map[string]bool, len(projects))
+	for i, p := range projects {
+		if dir, isReserved := reserved[p.name]; !kept[p.name] && (!isReserved || dir == p.dir) {
+			kept[p.name] = true
+			continue
+		}
+		for n := 2; ; n++ {
+			name := fmt.Sprintf("%s%s%d", p.name, nameSeparator, n)
+			if _, isReserved := reserved[name]; names[name] == 0 && !isReserved {
+				projects[i].name = name
+				names[name]++
+				kept[name] = true
+				break
+			}
+		}
+	}
+}
+
+func countNames(projects []smartProject) map[string]int {
+	counts := make(map[string]int, len(projects))
+	for _, p := range projects {
+		counts[p.name]++
+	}
+	return counts
+}
@@ -0,0 +1,244 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"api", "api"},
+		{"my.site", "my_site"},
+		{"host:8080", "host_8080"},
+		{"org/repo", "org/repo"},
+		{"api~work", "api~work"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := sanitizeName(tt.input); got != tt.want {
+				t.Errorf("sanitizeName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmartDirectory_SessionName(t *testing.T) {
+	root := "/home/me/src"
+	dir := "/home/me/src/github.com/acme/api.v2"
+
+	tests := []struct {
+		name string
+		sd   SmartDirectory
+		want string
+	}{
+		{name: "default", sd: SmartDirectory{}, want: "api_v2"},
+		{name: "prefix", sd: SmartDirectory{Prefix: "w-"}, want: "w-api_v2"},
+		{name: "parent and base", sd: SmartDirectory{NameTemplate: "{parent}/{base}"}, want: "acme/api_v2"},
+		{name: "root", sd: SmartDirectory{NameTemplate: "{root}-{base}"}, want: "src-api_v2"},
+		{name: "path", sd: SmartDirectory{NameTemplate: "{path}"}, want: "github_com/acme/api_v2"},
+		{name: "prefix and template", sd: SmartDirectory{Prefix: "gh:", NameTemplate: "{parent}/{base}"}, want: "gh_acme/api_v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sd.sessionName(root, dir); got != tt.want {
+				t.Errorf("sessionName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmartProjects_Disambiguate(t *testing.T) {
+	tmp := t.TempDir()
+	work := filepath.Join(tmp, "work")
+	projects := filepath.Join(tmp, "projects")
+	nested := filepath.Join(tmp, "nested")
+	for _, dir := range []string{
+		filepath.Join(work, "api"),
+		filepath.Join(work, "billing"),
+		filepath.Join(projects, "api"),
+		filepath.Join(projects, "tm"),
+		filepath.Join(projects, "notes"),
+		filepath.Join(tmp, "elsewhere", "notes"),
+		filepath.Join(tmp, "nested", "acme", "api"),
+		filepath.Join(tmp, "nested", "other", "api"),
+		filepath.Join(tmp, "nested", "other", "web"),
+		filepath.Join(tmp, "a", "work", "api"),
+		filepath.Join(tmp, "b", "work", "api"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		pre   []PreDefinedSession
+		smart []SmartDirectory
+		want  []string
+	}{
+		{
+			name:  "shared names get the smart directory name",
+			smart: []SmartDirectory{{Dir: work}, {Dir: projects}},
+			want:  []string{"api~work", "billing", "api~projects", "notes", "tm"},
+		},
+		{
+			name:  "nested projects in the same smart directory get their parent",
+			smart: []SmartDirectory{{Dir: nested, Depth: 2}},
+			want:  []string{"api~acme", "api~other", "web"},
+		},
+		{
+			name:  "smart directories with the same name are numbered",
+			smart: []SmartDirectory{{Dir: filepath.Join(tmp, "a", "work")}, {Dir: filepath.Join(tmp, "b", "work")}},
+			want:  []string{"api~work", "api~work~2"},
+		},
+		{
+			name:  "numbers skip names already in use",
+			pre:   []PreDefinedSession{{Name: "api~work~2", Dir: filepath.Join(tmp, "elsewhere", "notes")}},
+			smart: []SmartDirectory{{Dir: filepath.Join(tmp, "a", "work")}, {Dir: filepath.Join(tmp, "b", "work")}},
+			want:  []string{"api~work", "api~work~3"},
+		},
+		{
+			name:  "prefix avoids the collision",
+			smart: []SmartDirectory{{Dir: work, Prefix: "w-"}, {Dir: projects}},
+			want:  []string{"w-api", "w-billing", "api", "notes", "tm"},
+		},
+		{
+			name:  "pre-defined session for another directory",
+			pre:   []PreDefinedSession{{Name: "notes", Dir: filepath.Join(tmp, "elsewhere", "notes")}},
+			smart: []SmartDirectory{{Dir: projects}},
+			want:  []string{"api", "notes~projects", "tm"},
+		},
+		{
+			name:  "pre-defined session for the same directory",
+			pre:   []PreDefinedSession{{Name: "notes", Dir: filepath.Join(projects, "notes")}},
+			smart: []SmartDirectory{{Dir: projects}},
+			want:  []string{"api", "notes", "tm"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := NewFinder(&mockTmuxRepository{}, tt.pre, tt.smart, nil)
+			found, err := finder.smartProjects()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, p := range found {
+				got = append(got, p.name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("smartProjects() names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind_DisambiguatedNames(t *testing.T) {
+	tmp := t.TempDir()
+	work := filepath.Join(tmp, "work")
+	projects := filepath.Join(tmp, "projects")
+	for _, dir := range []string{
+		filepath.Join(work, "api"),
+		filepath.Join(projects, "api"),
+		filepath.Join(projects, "my.site"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	smart := []SmartDirectory{{Dir: work}, {Dir: projects}}
+
+	tests := []struct {
+		lookup   string
+		wantName string
+		wantDir  string
+	}{
+		{lookup: "api~work", wantName: "api~work", wantDir: filepath.Join(work, "api")},
+		{lookup: "api~projects", wantName: "api~projects", wantDir: filepath.Join(projects, "api")},
+		{lookup: "my_site", wantName: "my_site", wantDir: filepath.Join(projects, "my.site")},
+		{lookup: "my.site", wantName: "my_site", wantDir: filepath.Join(projects, "my.site")},
+		{lookup: "api", wantName: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lookup, func(t *testing.T) {
+			finder := NewFinder(&mockTmuxRepository{}, nil, smart, nil)
+			sess, err := finder.Find(tt.lookup)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantName == "" {
+				if sess != nil {
+					t.Errorf("expected ambiguous name to find nothing, got %+v", sess)
+				}
+				return
+			}
+			if sess == nil || sess.Name != tt.wantName || sess.Dir != tt.wantDir {
+				t.Errorf("Find(%q) = %+v, want %s in %s", tt.lookup, sess, tt.wantName, tt.wantDir)
+			}
+		})
+	}
+
+	t.Run("list keeps both", func(t *testing.T) {
+		finder := NewFinder(&mockTmuxRepository{}, nil, smart, nil)
+		var names []string
+		for _, s := range finder.List(false) {
+			names = append(names, s.Name)
+		}
+		if !slices.Equal(names, []string{"api~projects", "api~work", "my_site"}) {
+			t.Errorf("List() names = %v", names)
+		}
+	})
+
+	t.Run("smart directories with the same name stay reachable", func(t *testing.T) {
+		a, b := filepath.Join(tmp, "a", "work"), filepath.Join(tmp, "b", "work")
+		for _, dir := range []string{filepath.Join(a, "api"), filepath.Join(b, "api")} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: a}, {Dir: b}}, nil)
+		for lookup, want := range map[string]string{"api~work": filepath.Join(a, "api"), "api~work~2": filepath.Join(b, "api")} {
+			sess, err := finder.Find(lookup)
+			if err != nil || sess == nil || sess.Dir != want {
+				t.Errorf("Find(%q) = %+v, %v, want %s", lookup, sess, err, want)
+			}
+		}
+	})
+
+	t.Run("running session gets its directory", func(t *testing.T) {
+		repo := &mockTmuxRepository{allSessions: []*Session{{Name: "api~work", Exists: true}}}
+		finder := NewFinder(repo, nil, smart, nil)
+		listed := finder.List(true)
+		if len(listed) != 1 || listed[0].Dir != filepath.Join(work, "api") {
+			t.Errorf("unexpected running sessions %+v", listed)
+		}
+	})
+}
+
+func TestNewFinder_SanitizesPreDefinedNames(t *testing.T) {
+	tmp := t.TempDir()
+	pre := []PreDefinedSession{{Name: "my.app", Dir: tmp}}
+	finder := NewFinder(&mockTmuxRepository{}, pre, nil, nil)
+
+	for _, lookup := range []string{"my.app", "my_app"} {
+		sess, err := finder.Find(lookup)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sess == nil || sess.Name != "my_app" {
+			t.Errorf("Find(%q) = %+v, want my_app", lookup, sess)
+		}
+	}
+	if pre[0].Name != "my.app" {
+		t.Error("expected the caller's sessions to be left untouched")
+	}
+}
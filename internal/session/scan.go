@@ -0,0 +1,175 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName is the file inside a smart directory root that lists extra
+// ignore patterns, one per line.
+const ignoreFileName = ".tmignore"
+
+// depth returns how many levels below its root the smart directory is
+// scanned for projects. It is at least 1, the direct children, which is also
+// what an unset Depth scans.
+func (sd SmartDirectory) depth() int {
+	return max(sd.Depth, 1)
+}
+
+// isProject reports whether dir, found depth levels below the smart directory
+// root, is a project. With markers configured a project is any directory
+// containing one of them; without markers it is any directory at the
+// configured depth.
+func (sd SmartDirectory) isProject(dir string, depth int) bool {
+	if len(sd.Markers) == 0 {
+		return depth == sd.depth()
+	}
+	for _, marker := range sd.Markers {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// filter decides which directories below a smart directory root are scanned
+// and listed. Paths given to it are relative to the root and slash separated.
+type filter struct {
+	ignore     []string
+	include    []string
+	showHidden bool
+}
+
+// newFilter builds the filter for a smart directory, adding the patterns from
+// a .tmignore file at root to the configured ignore list.
+func newFilter(root string, sd SmartDirectory) filter {
+	f := filter{
+		ignore:     sd.Ignore,
+		include:    sd.Include,
+		showHidden: sd.ShowHidden,
+	}
+
+	file, err := os.Open(filepath.Join(root, ignoreFileName))
+	if err != nil {
+		return f
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f.ignore = append(f.ignore, line)
+	}
+	return f
+}
+
+// skip reports whether the directory at rel is hidden or ignored. Nothing
+// below a skipped directory is scanned.
+func (f filter) skip(rel string) bool {
+	if !f.showHidden && strings.HasPrefix(path.Base(rel), ".") {
+		return true
+	}
+	return matchAny(f.ignore, rel)
+}
+
+// included reports whether the project at rel passes the include list. An
+// empty list includes every project.
+func (f filter) included(rel string) bool {
+	return len(f.include) == 0 || matchAny(f.include, rel)
+}
+
+// allows reports whether the project at rel would be listed by a scan, i.e.
+// neither it nor any directory above it is skipped and it is included.
+func (f filter) allows(rel string) bool {
+	for dir := rel; dir != "."; dir = path.Dir(dir) {
+		if f.skip(dir) {
+			return false
+		}
+	}
+	return f.included(rel)
+}
+
+// matchAny reports whether rel matches any of the glob patterns. A pattern
+// containing a slash, or starting with one, matches the whole path relative to
+// the root; any other pattern matches the directory name at any depth. A
+// trailing slash is ignored since only directories are matched.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "/")
+		target := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+			target = rel
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scanProjects returns the project directories below root. Scanning does not
+// descend into a project once it is found, nor into hidden or ignored
+// directories.
+func scanProjects(root string, sd SmartDirectory) []string {
+	var projects []string
+	f := newFilter(root, sd)
+
+	var walk func(dir, rel string, depth int)
+	walk = func(dir, rel string, depth int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			entryRel := path.Join(rel, entry.Name())
+			if f.skip(entryRel) {
+				continue
+			}
+
+			entryDir := fmt.Sprintf("%s/%s", dir, entry.Name())
+			if sd.isProject(entryDir, depth) {
+				if f.included(entryRel) {
+					projects = append(projects, entryDir)
+				}
+				continue
+			}
+			if depth < sd.depth() {
+				walk(entryDir, entryRel, depth+1)
+			}
+		}
+	}
+
+	if dirExists(root) {
+		walk(root, "", 1)
+	}
+	return projects
+}
+
+// findProjectDir returns the project at the path rel below root, or an empty
+// string when that path is not a project a scan would list.
+func findProjectDir(root string, sd SmartDirectory, rel string) string {
+	if !filepath.IsLocal(rel) {
+		return ""
+	}
+
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	candidate := fmt.Sprintf("%s/%s", root, rel)
+	depth := strings.Count(rel, "/") + 1
+	if depth <= sd.depth() && dirExists(candidate) && sd.isProject(candidate, depth) && newFilter(root, sd).allows(rel) {
+		return candidate
+	}
+	return ""
+}
@@ -0,0 +1,265 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// makeTree creates the given directories below root and a marker file in
+// each directory listed in marked.
+func makeTree(t *testing.T, root string, dirs []string, marked map[string]string) {
+	t.Helper()
+	for _, d := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for d, marker := range marked {
+		if err := os.WriteFile(filepath.Join(root, d, marker), []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanProjects(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, []string{
+		"acme/api/internal",
+		"acme/web",
+		"acme/notes",
+		"solo",
+		"deep/a/b/c",
+	}, map[string]string{
+		"acme/api":          "go.mod",
+		"acme/web":          "package.json",
+		"solo":              "go.mod",
+		"deep/a/b/c":        "go.mod",
+		"acme/api/internal": "go.mod",
+	})
+	if err := os.WriteFile(filepath.Join(root, "README.md"), []byte(""), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		sd   SmartDirectory
+		want []string
+	}{
+		{
+			name: "default lists direct children",
+			sd:   SmartDirectory{},
+			want: []string{"acme", "deep", "solo"},
+		},
+		{
+			name: "depth without markers lists the configured level",
+			sd:   SmartDirectory{Depth: 2},
+			want: []string{"acme/api", "acme/notes", "acme/web", "deep/a"},
+		},
+		{
+			name: "markers stop descending once a project is found",
+			sd:   SmartDirectory{Depth: 3, Markers: []string{"go.mod", "package.json"}},
+			want: []string{"acme/api", "acme/web", "solo"},
+		},
+		{
+			name: "markers respect depth",
+			sd:   SmartDirectory{Depth: 4, Markers: []string{"go.mod"}},
+			want: []string{"acme/api", "deep/a/b/c", "solo"},
+		},
+		{
+			name: "markers at depth one",
+			sd:   SmartDirectory{Markers: []string{"go.mod"}},
+			want: []string{"solo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, dir := range scanProjects(root, tt.sd) {
+				rel, err := filepath.Rel(root, dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, rel)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("scanProjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing root", func(t *testing.T) {
+		if got := scanProjects(filepath.Join(root, "missing"), SmartDirectory{}); got != nil {
+			t.Errorf("expected nil, got %v", got)
+		}
+	})
+}
+
+func TestFindProjectDir(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, []string{"acme/api", "acme/notes", "solo"}, map[string]string{
+		"acme/api": ".git",
+		"solo":     ".git",
+	})
+	nested := SmartDirectory{Depth: 2, Markers: []string{".git"}}
+
+	tests := []struct {
+		name   string
+		sd     SmartDirectory
+		lookup string
+		want   string
+	}{
+		{name: "direct child", sd: SmartDirectory{}, lookup: "solo", want: "solo"},
+		{name: "base name is not a path", sd: nested, lookup: "api", want: ""},
+		{name: "nested project by relative path", sd: nested, lookup: "acme/api", want: "acme/api"},
+		{name: "shallow project with markers", sd: nested, lookup: "solo", want: "solo"},
+		{name: "directory without marker", sd: nested, lookup: "notes", want: ""},
+		{name: "org folder is not a project", sd: nested, lookup: "acme", want: ""},
+		{name: "nested project beyond default depth", sd: SmartDirectory{}, lookup: "acme/api", want: ""},
+		{name: "path outside root", sd: nested, lookup: "../solo", want: ""},
+		{name: "empty name", sd: nested, lookup: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findProjectDir(root, tt.sd, tt.lookup)
+			want := ""
+			if tt.want != "" {
+				want = root + "/" + tt.want
+			}
+			if got != want {
+				t.Errorf("findProjectDir(%q) = %q, want %q", tt.lookup, got, want)
+			}
+		})
+	}
+}
+
+func TestFind_NestedSmartDirectory(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, []string{"acme/api", "acme/web"}, map[string]string{
+		"acme/api": "go.mod",
+		"acme/web": "package.json",
+	})
+	smart := []SmartDirectory{{Dir: root, Depth: 2, Markers: []string{"go.mod", "package.json"}}}
+
+	finder := NewFinder(&mockTmuxRepository{}, nil, smart, nil)
+	sess, err := finder.Find("web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess == nil || sess.Name != "web" || sess.Dir != root+"/acme/web" {
+		t.Fatalf("unexpected session %+v", sess)
+	}
+
+	sess, err = finder.Find("acme/web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess == nil || sess.Name != "web" {
+		t.Fatalf("expected relative path to find web, got %+v", sess)
+	}
+
+	var names []string
+	for _, s := range finder.List(false) {
+		names = append(names, s.Name)
+	}
+	if !slices.Equal(names, []string{"api", "web"}) {
+		t.Errorf("List() names = %v, want [api web]", names)
+	}
+
+	running := NewFinder(&mockTmuxRepository{allSessions: []*Session{{Name: "api", Dir: root + "/acme/api", Exists: true}}}, nil, []SmartDirectory{{Dir: root, Depth: 2, Markers: []string{"go.mod"}, Hooks: Hooks{OnKill: []Hook{{Command: "stop"}}}}}, nil)
+	listed := running.List(true)
+	if len(listed) != 1 || len(listed[0].Hooks.OnKill) != 1 {
+		t.Errorf("expected running nested project to get smart directory hooks, got %+v", listed)
+	}
+}
+
+func TestScanProjects_Filters(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, []string{
+		"api",
+		"web/app",
+		"web/node_modules",
+		"node_modules",
+		".cache",
+		"archive/old",
+		"scratch-1",
+		"work-billing",
+	}, nil)
+	for _, dir := range []string{"api", "web/app", "web/node_modules", "node_modules", ".cache", "archive/old", "scratch-1", "work-billing"} {
+		if err := os.Mkdir(filepath.Join(root, dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".tmignore"), []byte("# scratch space\nscratch-*\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git := []string{".git"}
+
+	tests := []struct {
+		name string
+		sd   SmartDirectory
+		want []string
+	}{
+		{
+			name: "hidden directories and tmignore patterns are skipped",
+			sd:   SmartDirectory{},
+			want: []string{"api", "archive", "node_modules", "web", "work-billing"},
+		},
+		{
+			name: "show hidden",
+			sd:   SmartDirectory{ShowHidden: true},
+			want: []string{".cache", "api", "archive", "node_modules", "web", "work-billing"},
+		},
+		{
+			name: "ignore by name and by path",
+			sd:   SmartDirectory{Depth: 2, Markers: git, Ignore: []string{"node_modules", "/archive/"}},
+			want: []string{"api", "web/app", "work-billing"},
+		},
+		{
+			name: "ignored directories are not descended into",
+			sd:   SmartDirectory{Depth: 2, Markers: git, Ignore: []string{"archive"}},
+			want: []string{"api", "node_modules", "web/app", "web/node_modules", "work-billing"},
+		},
+		{
+			name: "include limits the listed projects",
+			sd:   SmartDirectory{Include: []string{"work-*", "api"}},
+			want: []string{"api", "work-billing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, dir := range scanProjects(root, tt.sd) {
+				rel, err := filepath.Rel(root, dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, rel)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("scanProjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("find skips filtered projects", func(t *testing.T) {
+		sd := SmartDirectory{Depth: 2, Markers: git, Ignore: []string{"node_modules"}, Include: []string{"api", "app", "scratch-*"}}
+		for name, want := range map[string]bool{
+			"api":              true,
+			"web/app":          true,
+			"web/node_modules": false,
+			"node_modules":     false,
+			"scratch-1":        false,
+			".cache":           false,
+			"work-billing":     false,
+		} {
+			if got := findProjectDir(root, sd, name) != ""; got != want {
+				t.Errorf("findProjectDir(%q) found = %v, want %v", name, got, want)
+			}
+		}
+	})
+}
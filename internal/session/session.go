@@ -2,7 +2,6 @@ package session
 
 import (
 	"cmp"
-	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
@@ -15,6 +14,17 @@ type Session struct {
 	Exists       bool
 	LastAttached int64
 	Aliases      []string
+	Source       Source
+	Root         string
+	Socket       string
+	Windows      []Window
+	Hooks        Hooks
+	Env          map[string]string
+	ProjectFile  *ProjectFile
+	// ProjectEnv is the env of ProjectFile. It is kept apart from Env until
+	// the app knows the project file is trusted, as variables such as
+	// BASH_ENV run code.
+	ProjectEnv map[string]string
 }
 
 func New(name string, dir string, exists bool, lastAttached int64) *Session {
@@ -26,37 +36,149 @@ func New(name string, dir string, exists bool, lastAttached int64) *Session {
 	}
 }
 
+// Source says where a session comes from. A running session that belongs to
+// a pre-defined session or a smart directory project takes the source of its
+// definition, so only sessions tm knows nothing about are SourceTmux. The
+// Root of a SourceSmartDirectory session is the smart directory it was
+// found in.
+type Source string
+
+const (
+	SourceTmux           Source = "tmux"
+	SourcePreDefined     Source = "pre-defined"
+	SourceSmartDirectory Source = "smart"
+)
+
+// Sources lists every Source in the order they are documented.
+var Sources = []Source{SourceTmux, SourcePreDefined, SourceSmartDirectory}
+
+// PreDefinedSession is a session defined in the config file. A non-empty
+// Socket puts it on that tmux server instead of the default one.
 type PreDefinedSession struct {
 	Dir     string
 	Name    string
 	Aliases []string
+	Socket  string
+	Windows []Window
+	Hooks   Hooks
+	Env     map[string]string
 }
 
+// Window describes a tmux window to build when a session is first created.
+// An empty Dir inherits the session directory, and relative paths are
+// resolved against it. Layout is passed to tmux select-layout as-is, so both
+// named layouts (main-vertical, tiled, ...) and custom layout strings work.
+// Command runs in the first pane when that pane has no command of its own.
+type Window struct {
+	Name    string
+	Dir     string
+	Layout  string
+	Command string
+	NoEnter bool
+	Panes   []Pane
+}
+
+// Pane describes a single pane inside a Window. An empty Dir inherits the
+// window directory, and relative paths are resolved against it. Command is
+// typed into the pane once the session is built, followed by Enter unless
+// NoEnter is set.
+type Pane struct {
+	Dir     string
+	Command string
+	NoEnter bool
+}
+
+// SmartDirectory is a directory whose projects become sessions. By default
+// every direct child is a project. Depth scans further down, and Markers
+// limits projects to directories containing one of the listed files or
+// directories. Hooks and Env apply to every session found in it. Sessions
+// are named after their directory unless Prefix or NameTemplate say
+// otherwise.
 type SmartDirectory struct {
-	Dir string
+	Dir          string
+	Depth        int
+	Markers      []string
+	Ignore       []string
+	Include      []string
+	ShowHidden   bool
+	Prefix       string
+	NameTemplate string
+	Hooks        Hooks
+	Env          map[string]string
 }
 
+// Hook is a shell command run at a point in a session's lifecycle. A failing
+// hook is reported, and also stops the attach or kill when Abort is set.
+type Hook struct {
+	Command string
+	Abort   bool
+}
+
+// Hooks groups the lifecycle hooks of a session. OnCreate runs before tmux
+// creates the session, OnAttach before every attach or switch, OnKill before
+// the session is killed, and OnDetach is handed to tmux to run whenever a
+// client detaches from a session tm created.
+type Hooks struct {
+	OnCreate []Hook
+	OnAttach []Hook
+	OnDetach []Hook
+	OnKill   []Hook
+}
+
+// Project is a project-local session definition shipped inside a smart
+// directory project, such as a .tm.yaml file at its root.
+type Project struct {
+	File    ProjectFile
+	Aliases []string
+	Windows []Window
+	Env     map[string]string
+}
+
+// ProjectFile identifies the file a Project was read from. Hash changes
+// whenever the content does, so a changed file can be asked about again.
+type ProjectFile struct {
+	Path string
+	Hash string
+}
+
+// TmuxRepository looks up running sessions. An empty socket is the default
+// server of the repository.
 type TmuxRepository interface {
-	HasSession(name string) bool
+	HasSession(name, socket string) bool
 	AllSessions() []*Session
 }
 
+// ProjectLoader loads the project-local definition in dir. It returns nil
+// when the directory has none.
+type ProjectLoader interface {
+	Load(dir string) (*Project, error)
+}
+
 type Finder struct {
 	repository         TmuxRepository
 	preDefinedSessions []PreDefinedSession
 	smartDirectories   []SmartDirectory
+	projectLoader      ProjectLoader
 }
 
-func NewFinder(r TmuxRepository, pds []PreDefinedSession, sd []SmartDirectory) *Finder {
+// NewFinder creates a Finder. pl may be nil to ignore project-local files.
+// Pre-defined session names are sanitised the same way tmux would.
+func NewFinder(r TmuxRepository, pds []PreDefinedSession, sd []SmartDirectory, pl ProjectLoader) *Finder {
+	pds = slices.Clone(pds)
+	for i := range pds {
+		pds[i].Name = sanitizeName(pds[i].Name)
+	}
 	return &Finder{
 		repository:         r,
 		preDefinedSessions: pds,
 		smartDirectories:   sd,
+		projectLoader:      pl,
 	}
 }
 
 func (f *Finder) Find(name string) (*Session, error) {
-	if session := f.findExistingSession(name); session != nil {
+	if session := f.findExistingSession(sanitizeName(name), ""); session != nil {
+		f.applyRunningDefinition(session)
 		return session, nil
 	}
 
@@ -68,7 +190,15 @@ func (f *Finder) Find(name string) (*Session, error) {
 		return session, nil
 	}
 
-	return f.findSmartSessionDirectorySession(name)
+	session, err = f.findSmartSessionDirectorySession(name)
+	if err != nil {
+		return nil, err
+	}
+	if session != nil {
+		return session, nil
+	}
+
+	return f.findProjectAlias(name), nil
 }
 
 func (f *Finder) ListExcluding(onlyExisting bool, exclude string) []*Session {
@@ -102,13 +232,10 @@ func (f *Finder) List(onlyExisting bool) []*Session {
 	var sessions []*Session
 	sessions = f.repository.AllSessions()
 
+	projects, _ := f.smartProjects()
 	for _, s := range sessions {
-		for _, pd := range f.preDefinedSessions {
-			if s.Name == pd.Name {
-				s.Aliases = pd.Aliases
-				break
-			}
-		}
+		s.Source = SourceTmux
+		f.applyDefinition(s, projects)
 	}
 
 	slices.SortFunc(sessions, func(a, b *Session) int {
@@ -121,7 +248,7 @@ func (f *Finder) List(onlyExisting bool) []*Session {
 
 		for _, pd := range f.getAllPreDefinedSessions() {
 			foundInExisting := slices.ContainsFunc(sessions, func(s *Session) bool {
-				return s.Name == pd.Name
+				return s.Name == pd.Name && s.Socket == pd.Socket
 			})
 
 			if !foundInExisting {
@@ -129,9 +256,9 @@ func (f *Finder) List(onlyExisting bool) []*Session {
 			}
 		}
 
-		for _, sd := range f.getAllSmartSessionDirectorySessions() {
+		for _, sd := range f.smartSessions(projects) {
 			foundInTmux := slices.ContainsFunc(sessions, func(s *Session) bool {
-				return s.Name == sd.Name
+				return s.Name == sd.Name && s.Socket == ""
 			})
 			foundInNonExisting := slices.ContainsFunc(nonExisting, func(s *Session) bool {
 				return s.Dir == sd.Dir
@@ -151,22 +278,115 @@ func (f *Finder) List(onlyExisting bool) []*Session {
 	return sessions
 }
 
-func (f *Finder) findExistingSession(name string) *Session {
-	if f.repository.HasSession(name) {
-		return New(name, "", true, 0)
+func (f *Finder) findExistingSession(name, socket string) *Session {
+	if f.repository.HasSession(name, socket) {
+		s := New(name, "", true, 0)
+		s.Source = SourceTmux
+		s.Socket = socket
+		return s
 	}
 	return nil
 }
 
+// applyDefinition copies the aliases, hooks and directory of the pre-defined
+// session or smart directory project a running session belongs to. Smart
+// directory projects only live on the default server.
+func (f *Finder) applyDefinition(s *Session, projects []smartProject) {
+	if f.applyPreDefinedSession(s) || s.Socket != "" {
+		return
+	}
+	for _, p := range projects {
+		if p.name == s.Name && (s.Dir == p.dir || s.Dir == "") {
+			f.applySmartProject(s, p)
+			return
+		}
+	}
+}
+
+// applyRunningDefinition is applyDefinition for a single running session.
+// Instead of scanning every smart directory it looks up the project in the
+// directory tmux reports for the session, and only scans when tmux reports
+// none.
+func (f *Finder) applyRunningDefinition(s *Session) {
+	if f.applyPreDefinedSession(s) || s.Socket != "" {
+		return
+	}
+	for _, running := range f.repository.AllSessions() {
+		if running.Name == s.Name && running.Socket == "" {
+			s.Dir = running.Dir
+			break
+		}
+	}
+	if s.Dir == "" {
+		projects, _ := f.smartProjects()
+		f.applyDefinition(s, projects)
+		return
+	}
+	if p, ok := f.projectAt(s); ok {
+		f.applySmartProject(s, p)
+	}
+}
+
+// applyPreDefinedSession applies the pre-defined session with the name and
+// socket of s, and reports whether there is one.
+func (f *Finder) applyPreDefinedSession(s *Session) bool {
+	for _, pd := range f.preDefinedSessions {
+		if s.Name != pd.Name || s.Socket != pd.Socket {
+			continue
+		}
+		s.Source = SourcePreDefined
+		s.Aliases = pd.Aliases
+		s.Hooks = pd.Hooks
+		if dir, err := expandHomeDir(pd.Dir); err == nil && s.Dir == "" {
+			s.Dir = filepath.Clean(dir)
+		}
+		return true
+	}
+	return false
+}
+
+func (f *Finder) applySmartProject(s *Session, p smartProject) {
+	s.Source = SourceSmartDirectory
+	s.Root = p.root
+	s.Dir = p.dir
+	s.Hooks = p.sd.Hooks
+	_ = f.applyProject(s)
+}
+
+// projectAt returns the smart directory project in the directory of the
+// running session s without scanning. The session has to carry the name the
+// project gets, or that name with a suffix disambiguate adds.
+func (f *Finder) projectAt(s *Session) (smartProject, bool) {
+	for _, sd := range f.smartDirectories {
+		root, err := expandHomeDir(sd.Dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, s.Dir)
+		if err != nil {
+			continue
+		}
+		dir := findProjectDir(root, sd, rel)
+		if dir == "" {
+			continue
+		}
+		if name := sd.sessionName(root, dir); s.Name == name || strings.HasPrefix(s.Name, name+nameSeparator) {
+			return smartProject{sd: sd, root: root, dir: dir, name: s.Name}, true
+		}
+	}
+	return smartProject{}, false
+}
+
 func (f *Finder) findPreDefinedSession(name string) (*Session, error) {
 	for _, pd := range f.preDefinedSessions {
-		found := slices.Contains(nameToMatch(pd), name)
+		names := nameToMatch(pd)
+		found := slices.Contains(names, name) || slices.Contains(names, sanitizeName(name))
 		if !found {
 			continue
 		}
 
-		if existing := f.findExistingSession(pd.Name); existing != nil {
-			existing.Aliases = pd.Aliases
+		if existing := f.findExistingSession(pd.Name, pd.Socket); existing != nil {
+			f.applyDefinition(existing, nil)
 			return existing, nil
 		}
 
@@ -182,24 +402,109 @@ func (f *Finder) findPreDefinedSession(name string) (*Session, error) {
 		}
 
 		s := New(pd.Name, dir, false, 0)
+		s.Source = SourcePreDefined
 		s.Aliases = pd.Aliases
+		s.Socket = pd.Socket
+		s.Hooks = pd.Hooks
+		s.Windows, err = resolveWindows(dir, pd.Windows)
+		if err != nil {
+			return nil, err
+		}
+		s.Env, err = resolveEnv(pd.Env)
+		if err != nil {
+			return nil, err
+		}
 		return s, nil
 	}
 	return nil, nil
 }
 
+// findSmartSessionDirectorySession finds the smart directory project with
+// the given session name. A name containing a slash is also looked up as a
+// path relative to each smart directory, e.g. org/repo.
 func (f *Finder) findSmartSessionDirectorySession(name string) (*Session, error) {
-	for _, sd := range f.smartDirectories {
-		dir, err := expandHomeDir(fmt.Sprintf("%s/%s", sd.Dir, name))
-		if err != nil {
-			return nil, err
+	projects, err := f.smartProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	match := slices.IndexFunc(projects, func(p smartProject) bool {
+		return p.name == sanitizeName(name)
+	})
+	if match < 0 && strings.Contains(name, "/") {
+		for _, sd := range f.smartDirectories {
+			root, err := expandHomeDir(sd.Dir)
+			if err != nil {
+				continue
+			}
+			if dir := findProjectDir(root, sd, name); dir != "" {
+				match = slices.IndexFunc(projects, func(p smartProject) bool {
+					return p.dir == dir
+				})
+				break
+			}
 		}
+	}
+	if match < 0 {
+		return nil, nil
+	}
 
-		if dirExists(dir) {
-			return New(name, dir, false, 0), nil
+	s, err := newSmartSession(projects[match])
+	if err != nil {
+		return nil, err
+	}
+	if err := f.applyProject(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// findProjectAlias finds a smart directory session whose project file
+// declares name as an alias.
+func (f *Finder) findProjectAlias(name string) *Session {
+	if f.projectLoader == nil {
+		return nil
+	}
+	projects, _ := f.smartProjects()
+	for _, s := range f.smartSessions(projects) {
+		if slices.Contains(s.Aliases, name) {
+			if existing := f.findExistingSession(s.Name, ""); existing != nil {
+				f.applyDefinition(existing, projects)
+				return existing
+			}
+			return s
 		}
 	}
-	return nil, nil
+	return nil
+}
+
+// applyProject applies the project-local definition found in the session
+// directory, if any. Its env goes in ProjectEnv, not Env.
+func (f *Finder) applyProject(s *Session) error {
+	if f.projectLoader == nil {
+		return nil
+	}
+
+	p, err := f.projectLoader.Load(s.Dir)
+	if err != nil || p == nil {
+		return err
+	}
+
+	windows, err := resolveWindows(s.Dir, p.Windows)
+	if err != nil {
+		return err
+	}
+	env, err := resolveEnv(p.Env)
+	if err != nil {
+		return err
+	}
+
+	s.Aliases = p.Aliases
+	s.Windows = windows
+	s.ProjectEnv = env
+	file := p.File
+	s.ProjectFile = &file
+	return nil
 }
 
 func (f *Finder) getAllPreDefinedSessions() []*Session {
@@ -210,40 +515,62 @@ func (f *Finder) getAllPreDefinedSessions() []*Session {
 			continue
 		}
 		dir = filepath.Clean(dir)
+		windows, err := resolveWindows(dir, pd.Windows)
+		if err != nil {
+			continue
+		}
+		env, err := resolveEnv(pd.Env)
+		if err != nil {
+			continue
+		}
 		s := New(pd.Name, dir, false, 0)
+		s.Source = SourcePreDefined
 		s.Aliases = pd.Aliases
+		s.Socket = pd.Socket
+		s.Windows = windows
+		s.Hooks = pd.Hooks
+		s.Env = env
 		sessions = append(sessions, s)
 	}
 	return sessions
 }
 
 func (f *Finder) getAllSmartSessionDirectorySessions() []*Session {
+	projects, _ := f.smartProjects()
+	return f.smartSessions(projects)
+}
+
+// smartSessions returns a session for each project, skipping those whose
+// smart directory env cannot be resolved.
+func (f *Finder) smartSessions(projects []smartProject) []*Session {
 	var sessions []*Session
-	for _, sd := range f.smartDirectories {
-		dir, err := expandHomeDir(sd.Dir)
+	for _, p := range projects {
+		s, err := newSmartSession(p)
 		if err != nil {
 			continue
 		}
-
-		if dirExists(dir) {
-			files, err := os.ReadDir(dir)
-			if err != nil {
-				continue
-			}
-
-			for _, file := range files {
-				if !file.IsDir() {
-					continue
-				}
-
-				sessions = append(sessions, New(file.Name(), fmt.Sprintf("%s/%s", dir, file.Name()), false, 0))
-			}
-		}
+		// A broken project file should not hide the project from the list.
+		_ = f.applyProject(s)
+		sessions = append(sessions, s)
 	}
 
 	return sessions
 }
 
+// newSmartSession returns a new session for a smart directory project.
+func newSmartSession(p smartProject) (*Session, error) {
+	env, err := resolveEnv(p.sd.Env)
+	if err != nil {
+		return nil, err
+	}
+	s := New(p.name, p.dir, false, 0)
+	s.Source = SourceSmartDirectory
+	s.Root = p.root
+	s.Hooks = p.sd.Hooks
+	s.Env = env
+	return s, nil
+}
+
 func nameToMatch(pds PreDefinedSession) []string {
 	var names []string
 
@@ -253,6 +580,72 @@ func nameToMatch(pds PreDefinedSession) []string {
 	return names
 }
 
+// resolveWindows returns a copy of windows with every window and pane
+// directory expanded and made absolute, inheriting from the parent when empty.
+func resolveWindows(sessionDir string, windows []Window) ([]Window, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	resolved := make([]Window, len(windows))
+	for i, w := range windows {
+		dir, err := resolveDir(sessionDir, w.Dir)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved[i] = w
+		resolved[i].Dir = dir
+		resolved[i].Panes = nil
+		for _, p := range w.Panes {
+			paneDir, err := resolveDir(dir, p.Dir)
+			if err != nil {
+				return nil, err
+			}
+			p.Dir = paneDir
+			resolved[i].Panes = append(resolved[i].Panes, p)
+		}
+	}
+	return resolved, nil
+}
+
+func resolveDir(parent, dir string) (string, error) {
+	if dir == "" {
+		return parent, nil
+	}
+	dir, err := expandHomeDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(parent, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// resolveEnv returns a copy of env with ~ expanded at the start of each
+// colon-separated element, as a shell does for assignments, and ${VAR}
+// references replaced from the current environment.
+func resolveEnv(env map[string]string) (map[string]string, error) {
+	if len(env) == 0 {
+		return nil, nil
+	}
+
+	resolved := make(map[string]string, len(env))
+	for k, v := range env {
+		parts := strings.Split(v, ":")
+		for i, part := range parts {
+			expanded, err := expandHomeDir(part)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = expanded
+		}
+		resolved[k] = os.ExpandEnv(strings.Join(parts, ":"))
+	}
+	return resolved, nil
+}
+
 func dirExists(path string) bool {
 	info, err := os.Stat(path)
 	return err == nil && info.IsDir()
@@ -1,8 +1,11 @@
 package session
 
 import (
+	"errors"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -11,7 +14,7 @@ type mockTmuxRepository struct {
 	allSessions []*Session
 }
 
-func (m *mockTmuxRepository) HasSession(name string) bool {
+func (m *mockTmuxRepository) HasSession(name, socket string) bool {
 	return m.hasSession
 }
 
@@ -19,12 +22,21 @@ func (m *mockTmuxRepository) AllSessions() []*Session {
 	return m.allSessions
 }
 
+type mockProjectLoader struct {
+	projects map[string]*Project
+	err      error
+}
+
+func (m *mockProjectLoader) Load(dir string) (*Project, error) {
+	return m.projects[dir], m.err
+}
+
 func TestFindExistingSession(t *testing.T) {
 	t.Run("existing session found", func(t *testing.T) {
 		checker := &mockTmuxRepository{hasSession: true}
-		finder := NewFinder(checker, nil, nil)
+		finder := NewFinder(checker, nil, nil, nil)
 
-		sess := finder.findExistingSession("test")
+		sess := finder.findExistingSession("test", "")
 		if sess == nil {
 			t.Fatal("expected session, got nil")
 		}
@@ -38,9 +50,9 @@ func TestFindExistingSession(t *testing.T) {
 
 	t.Run("no existing session", func(t *testing.T) {
 		checker := &mockTmuxRepository{hasSession: false}
-		finder := NewFinder(checker, nil, nil)
+		finder := NewFinder(checker, nil, nil, nil)
 
-		sess := finder.findExistingSession("test")
+		sess := finder.findExistingSession("test", "")
 		if sess != nil {
 			t.Errorf("expected nil, got %v", sess)
 		}
@@ -98,7 +110,7 @@ func TestFindPreDefinedSession(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			finder := NewFinder(&mockTmuxRepository{}, tt.pre, nil)
+			finder := NewFinder(&mockTmuxRepository{}, tt.pre, nil, nil)
 			sess, err := finder.findPreDefinedSession(tt.lookup)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
@@ -131,7 +143,7 @@ func TestFindPreDefinedSession_AliasesPopulated(t *testing.T) {
 		{Name: "myapp", Dir: validDir, Aliases: []string{"ma", "m"}},
 	}
 
-	finder := NewFinder(&mockTmuxRepository{}, pre, nil)
+	finder := NewFinder(&mockTmuxRepository{}, pre, nil, nil)
 
 	t.Run("new session has aliases", func(t *testing.T) {
 		sess, err := finder.findPreDefinedSession("myapp")
@@ -163,7 +175,7 @@ func TestFindPreDefinedSession_AliasesPopulated(t *testing.T) {
 	})
 
 	t.Run("existing session lookup has aliases", func(t *testing.T) {
-		finderWithExisting := NewFinder(&mockTmuxRepository{hasSession: true}, pre, nil)
+		finderWithExisting := NewFinder(&mockTmuxRepository{hasSession: true}, pre, nil, nil)
 		sess, err := finderWithExisting.findPreDefinedSession("myapp")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -177,6 +189,374 @@ func TestFindPreDefinedSession_AliasesPopulated(t *testing.T) {
 	})
 }
 
+func TestFindPreDefinedSession_WindowsResolved(t *testing.T) {
+	tmp := t.TempDir()
+
+	pre := []PreDefinedSession{
+		{
+			Name: "myapp",
+			Dir:  tmp,
+			Windows: []Window{
+				{Name: "editor", Layout: "tiled", Panes: []Pane{{}, {Dir: "api"}}},
+				{Name: "logs", Dir: "/var/log"},
+			},
+		},
+	}
+
+	finder := NewFinder(&mockTmuxRepository{}, pre, nil, nil)
+	sess, err := finder.findPreDefinedSession("myapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess == nil {
+		t.Fatal("expected session, got nil")
+	}
+	if len(sess.Windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(sess.Windows))
+	}
+
+	editor := sess.Windows[0]
+	if editor.Dir != tmp || editor.Layout != "tiled" {
+		t.Errorf("unexpected editor window %+v", editor)
+	}
+	if len(editor.Panes) != 2 || editor.Panes[0].Dir != tmp || editor.Panes[1].Dir != filepath.Join(tmp, "api") {
+		t.Errorf("unexpected editor panes %+v", editor.Panes)
+	}
+	if sess.Windows[1].Dir != "/var/log" {
+		t.Errorf("expected logs dir /var/log, got %s", sess.Windows[1].Dir)
+	}
+	if pre[0].Windows[0].Panes[1].Dir != "api" {
+		t.Error("expected pre-defined windows to be left untouched")
+	}
+}
+
+func TestResolveWindows(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("home directory unavailable")
+	}
+
+	t.Run("no windows", func(t *testing.T) {
+		got, err := resolveWindows("/src", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != nil {
+			t.Errorf("expected nil, got %v", got)
+		}
+	})
+
+	t.Run("home and relative dirs", func(t *testing.T) {
+		got, err := resolveWindows("/src", []Window{
+			{Dir: "~/notes", Panes: []Pane{{Dir: "../other"}}},
+			{Dir: "sub/"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got[0].Dir != filepath.Join(home, "notes") {
+			t.Errorf("expected %s, got %s", filepath.Join(home, "notes"), got[0].Dir)
+		}
+		if got[0].Panes[0].Dir != filepath.Join(home, "other") {
+			t.Errorf("expected %s, got %s", filepath.Join(home, "other"), got[0].Panes[0].Dir)
+		}
+		if got[1].Dir != "/src/sub" {
+			t.Errorf("expected /src/sub, got %s", got[1].Dir)
+		}
+	})
+
+	t.Run("home dir error", func(t *testing.T) {
+		t.Setenv("HOME", "")
+		_, err := resolveWindows("/src", []Window{{Dir: "~/notes"}})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestFind_HooksPopulated(t *testing.T) {
+	tmp := t.TempDir()
+	projectDir := filepath.Join(tmp, "myproject")
+	os.Mkdir(projectDir, 0755)
+
+	pdHooks := Hooks{OnAttach: []Hook{{Command: "vpn up"}}}
+	sdHooks := Hooks{OnCreate: []Hook{{Command: "direnv allow"}}}
+	pre := []PreDefinedSession{{Name: "predefined", Dir: tmp, Hooks: pdHooks}}
+	smart := []SmartDirectory{{Dir: tmp, Hooks: sdHooks}}
+
+	tests := []struct {
+		name      string
+		running   bool
+		lookup    string
+		wantHooks Hooks
+		wantDir   string
+	}{
+		{name: "new pre-defined session", lookup: "predefined", wantHooks: pdHooks, wantDir: tmp},
+		{name: "running pre-defined session", running: true, lookup: "predefined", wantHooks: pdHooks, wantDir: tmp},
+		{name: "new smart directory session", lookup: "myproject", wantHooks: sdHooks, wantDir: projectDir},
+		{name: "running smart directory session", running: true, lookup: "myproject", wantHooks: sdHooks, wantDir: projectDir},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := NewFinder(&mockTmuxRepository{hasSession: tt.running}, pre, smart, nil)
+			sess, err := finder.Find(tt.lookup)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sess == nil {
+				t.Fatal("expected session, got nil")
+			}
+			if len(sess.Hooks.OnAttach) != len(tt.wantHooks.OnAttach) || len(sess.Hooks.OnCreate) != len(tt.wantHooks.OnCreate) {
+				t.Errorf("expected hooks %+v, got %+v", tt.wantHooks, sess.Hooks)
+			}
+			if sess.Dir != tt.wantDir {
+				t.Errorf("expected dir %s, got %s", tt.wantDir, sess.Dir)
+			}
+		})
+	}
+}
+
+func TestFind_RunningSessionInProjectDir(t *testing.T) {
+	tmp := t.TempDir()
+	for _, dir := range []string{"work/api", "projects/api", "notes"} {
+		os.MkdirAll(filepath.Join(tmp, dir), 0755)
+	}
+
+	sdHooks := Hooks{OnAttach: []Hook{{Command: "direnv allow"}}}
+	smart := []SmartDirectory{{Dir: filepath.Join(tmp, "work"), Hooks: sdHooks}, {Dir: filepath.Join(tmp, "projects")}}
+	running := []*Session{
+		{Name: "api~work", Dir: filepath.Join(tmp, "work/api"), Exists: true},
+		{Name: "scratch", Dir: filepath.Join(tmp, "work/api"), Exists: true},
+		{Name: "notes", Dir: filepath.Join(tmp, "notes"), Exists: true},
+	}
+
+	tests := []struct {
+		lookup     string
+		wantSource Source
+		wantRoot   string
+	}{
+		{lookup: "api~work", wantSource: SourceSmartDirectory, wantRoot: filepath.Join(tmp, "work")},
+		{lookup: "scratch", wantSource: SourceTmux},
+		{lookup: "notes", wantSource: SourceTmux},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lookup, func(t *testing.T) {
+			finder := NewFinder(&mockTmuxRepository{hasSession: true, allSessions: running}, nil, smart, nil)
+			sess, err := finder.Find(tt.lookup)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sess.Source != tt.wantSource || sess.Root != tt.wantRoot {
+				t.Errorf("Find(%q) source %q root %q, want %q root %q", tt.lookup, sess.Source, sess.Root, tt.wantSource, tt.wantRoot)
+			}
+			if wantHooks := tt.wantSource == SourceSmartDirectory; (len(sess.Hooks.OnAttach) == 1) != wantHooks {
+				t.Errorf("Find(%q) hooks = %+v", tt.lookup, sess.Hooks)
+			}
+			if sess.Dir == "" {
+				t.Errorf("Find(%q) expected the directory tmux reports", tt.lookup)
+			}
+		})
+	}
+}
+
+func TestList_ExistingSessionsGetHooks(t *testing.T) {
+	tmp := t.TempDir()
+	projectDir := filepath.Join(tmp, "myproject")
+	os.Mkdir(projectDir, 0755)
+
+	repo := &mockTmuxRepository{allSessions: []*Session{
+		{Name: "predefined", Dir: "/elsewhere", Exists: true},
+		{Name: "myproject", Dir: projectDir, Exists: true},
+		{Name: "scratch", Dir: "/tmp", Exists: true},
+	}}
+	pre := []PreDefinedSession{{Name: "predefined", Dir: tmp, Hooks: Hooks{OnKill: []Hook{{Command: "down"}}}}}
+	smart := []SmartDirectory{{Dir: tmp, Hooks: Hooks{OnKill: []Hook{{Command: "stop"}}}}}
+
+	sessions := NewFinder(repo, pre, smart, nil).List(true)
+	got := map[string]int{}
+	for _, s := range sessions {
+		got[s.Name] = len(s.Hooks.OnKill)
+	}
+	if got["predefined"] != 1 || got["myproject"] != 1 || got["scratch"] != 0 {
+		t.Errorf("unexpected hooks per session %v", got)
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("home directory unavailable")
+	}
+	t.Setenv("TM_TEST_REGION", "eu-west-1")
+
+	t.Run("no env", func(t *testing.T) {
+		got, err := resolveEnv(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != nil {
+			t.Errorf("expected nil, got %v", got)
+		}
+	})
+
+	t.Run("expands home and variables", func(t *testing.T) {
+		env := map[string]string{
+			"KUBECONFIG":  "~/.kube/a:~/.kube/b",
+			"AWS_REGION":  "${TM_TEST_REGION}",
+			"AWS_PROFILE": "work",
+			"NOT_HOME":    "a~b",
+		}
+		got, err := resolveEnv(env)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]string{
+			"KUBECONFIG":  home + "/.kube/a:" + home + "/.kube/b",
+			"AWS_REGION":  "eu-west-1",
+			"AWS_PROFILE": "work",
+			"NOT_HOME":    "a~b",
+		}
+		if !maps.Equal(got, want) {
+			t.Errorf("resolveEnv() = %v, want %v", got, want)
+		}
+		if env["KUBECONFIG"] != "~/.kube/a:~/.kube/b" {
+			t.Error("expected input env to be left untouched")
+		}
+	})
+
+	t.Run("home dir error", func(t *testing.T) {
+		t.Setenv("HOME", "")
+		_, err := resolveEnv(map[string]string{"KUBECONFIG": "~/.kube/config"})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestFind_EnvPopulated(t *testing.T) {
+	tmp := t.TempDir()
+	projectDir := filepath.Join(tmp, "myproject")
+	os.Mkdir(projectDir, 0755)
+	t.Setenv("TM_TEST_PROFILE", "work")
+
+	pre := []PreDefinedSession{{Name: "predefined", Dir: tmp, Env: map[string]string{"AWS_PROFILE": "${TM_TEST_PROFILE}"}}}
+	smart := []SmartDirectory{{Dir: tmp, Env: map[string]string{"GOFLAGS": "-mod=mod"}}}
+	finder := NewFinder(&mockTmuxRepository{}, pre, smart, nil)
+
+	sess, err := finder.Find("predefined")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess.Env["AWS_PROFILE"] != "work" {
+		t.Errorf("expected AWS_PROFILE=work, got %v", sess.Env)
+	}
+
+	sess, err = finder.Find("myproject")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess.Env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("expected GOFLAGS=-mod=mod, got %v", sess.Env)
+	}
+
+	for _, s := range finder.List(false) {
+		if s.Name == "myproject" && s.Env["GOFLAGS"] != "-mod=mod" {
+			t.Errorf("expected listed smart session to carry env, got %v", s.Env)
+		}
+		if s.Name == "predefined" && s.Env["AWS_PROFILE"] != "work" {
+			t.Errorf("expected listed pre-defined session to carry env, got %v", s.Env)
+		}
+	}
+}
+
+func TestFind_ProjectFile(t *testing.T) {
+	tmp := t.TempDir()
+	apiDir := filepath.Join(tmp, "api")
+	webDir := filepath.Join(tmp, "web")
+	os.Mkdir(apiDir, 0755)
+	os.Mkdir(webDir, 0755)
+
+	loader := &mockProjectLoader{projects: map[string]*Project{
+		apiDir: {
+			File:    ProjectFile{Path: filepath.Join(apiDir, ".tm.yaml"), Hash: "abc"},
+			Aliases: []string{"be"},
+			Windows: []Window{{Name: "editor", Panes: []Pane{{Command: "nvim ."}, {Dir: "cmd"}}}},
+			Env:     map[string]string{"AWS_PROFILE": "api"},
+		},
+	}}
+	smart := []SmartDirectory{{Dir: tmp, Env: map[string]string{"AWS_PROFILE": "default", "GOFLAGS": "-mod=mod"}}}
+
+	t.Run("project file is applied", func(t *testing.T) {
+		finder := NewFinder(&mockTmuxRepository{}, nil, smart, loader)
+		sess, err := finder.Find("api")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sess.ProjectFile == nil || sess.ProjectFile.Hash != "abc" {
+			t.Errorf("expected project file, got %+v", sess.ProjectFile)
+		}
+		if len(sess.Aliases) != 1 || sess.Aliases[0] != "be" {
+			t.Errorf("unexpected aliases %v", sess.Aliases)
+		}
+		if len(sess.Windows) != 1 || sess.Windows[0].Dir != apiDir || sess.Windows[0].Panes[1].Dir != filepath.Join(apiDir, "cmd") {
+			t.Errorf("unexpected windows %+v", sess.Windows)
+		}
+		if sess.Env["AWS_PROFILE"] != "default" || sess.Env["GOFLAGS"] != "-mod=mod" {
+			t.Errorf("expected the smart directory env until the project file is trusted, got %v", sess.Env)
+		}
+		if sess.ProjectEnv["AWS_PROFILE"] != "api" {
+			t.Errorf("expected project env to be kept apart, got %v", sess.ProjectEnv)
+		}
+		if smart[0].Env["AWS_PROFILE"] != "default" {
+			t.Error("expected smart directory env to be left untouched")
+		}
+	})
+
+	t.Run("project without file keeps defaults", func(t *testing.T) {
+		finder := NewFinder(&mockTmuxRepository{}, nil, smart, loader)
+		sess, err := finder.Find("web")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sess.ProjectFile != nil || len(sess.Windows) != 0 {
+			t.Errorf("expected default session, got %+v", sess)
+		}
+	})
+
+	t.Run("find by project alias", func(t *testing.T) {
+		finder := NewFinder(&mockTmuxRepository{}, nil, smart, loader)
+		sess, err := finder.Find("be")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sess == nil || sess.Name != "api" {
+			t.Fatalf("expected api session, got %+v", sess)
+		}
+	})
+
+	t.Run("list shows project aliases", func(t *testing.T) {
+		finder := NewFinder(&mockTmuxRepository{}, nil, smart, loader)
+		for _, s := range finder.List(false) {
+			if s.Name == "api" && (len(s.Aliases) != 1 || s.Aliases[0] != "be") {
+				t.Errorf("expected listed aliases [be], got %v", s.Aliases)
+			}
+		}
+	})
+
+	t.Run("loader error is returned by find but not list", func(t *testing.T) {
+		broken := &mockProjectLoader{err: errors.New("invalid project file")}
+		finder := NewFinder(&mockTmuxRepository{}, nil, smart, broken)
+		if _, err := finder.Find("api"); err == nil {
+			t.Error("expected error from Find")
+		}
+		if got := finder.List(false); len(got) != 2 {
+			t.Errorf("expected both projects listed, got %d", len(got))
+		}
+	})
+}
+
 func TestNameToMatch(t *testing.T) {
 	pds := PreDefinedSession{
 		Name:    "main",
@@ -228,7 +608,7 @@ func TestFindSmartSessionDirectories(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			finder := NewFinder(&mockTmuxRepository{}, nil, tt.smart)
+			finder := NewFinder(&mockTmuxRepository{}, nil, tt.smart, nil)
 			sess, err := finder.findSmartSessionDirectorySession(tt.lookup)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
@@ -308,7 +688,7 @@ func TestServiceFind(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			finder := NewFinder(tt.checker, tt.pre, tt.smart)
+			finder := NewFinder(tt.checker, tt.pre, tt.smart, nil)
 			sess, err := finder.Find(tt.lookup)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
@@ -345,7 +725,7 @@ func TestListExcluding(t *testing.T) {
 		{Name: "other", Dir: predefinedDir},
 	}
 
-	finder := NewFinder(&mockTmuxRepository{allSessions: existing}, pre, nil)
+	finder := NewFinder(&mockTmuxRepository{allSessions: existing}, pre, nil, nil)
 
 	tests := []struct {
 		name         string
@@ -418,7 +798,7 @@ func TestList_PredefinedAliasesNotDuplicated(t *testing.T) {
 		{Name: "myapp", Dir: predefinedDir, Aliases: []string{"ma", "m"}},
 	}
 
-	finder := NewFinder(&mockTmuxRepository{}, pre, nil)
+	finder := NewFinder(&mockTmuxRepository{}, pre, nil, nil)
 	got := finder.List(false)
 
 	if len(got) != 1 {
@@ -441,7 +821,7 @@ func TestList_ExistingPredefinedSessionRetainsAliases(t *testing.T) {
 		{Name: "myapp", Dir: "/tmp/myapp", Aliases: []string{"ma", "m"}},
 	}
 
-	finder := NewFinder(&mockTmuxRepository{allSessions: existing}, pre, nil)
+	finder := NewFinder(&mockTmuxRepository{allSessions: existing}, pre, nil, nil)
 	got := finder.List(true)
 
 	if len(got) != 1 {
@@ -463,7 +843,7 @@ func TestList_SortedByLastAttached(t *testing.T) {
 		{Name: "middle", Dir: "/tmp/middle", Exists: true, LastAttached: 2000},
 	}
 
-	finder := NewFinder(&mockTmuxRepository{allSessions: sessions}, nil, nil)
+	finder := NewFinder(&mockTmuxRepository{allSessions: sessions}, nil, nil, nil)
 	got := finder.List(true)
 
 	wantOrder := []string{"newest", "middle", "oldest", "never"}
@@ -501,7 +881,7 @@ func TestList_MixedSessionsOrdering(t *testing.T) {
 
 	smart := []SmartDirectory{{Dir: smartTmp}}
 
-	finder := NewFinder(&mockTmuxRepository{allSessions: existing}, pre, smart)
+	finder := NewFinder(&mockTmuxRepository{allSessions: existing}, pre, smart, nil)
 	got := finder.List(false)
 
 	wantOrder := []string{"existing-newest", "existing-oldest", "predefined-a", "predefined-m", "smart-b", "smart-c", "smart-z"}
@@ -515,6 +895,102 @@ func TestList_MixedSessionsOrdering(t *testing.T) {
 	}
 }
 
+func TestList_Source(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{"pre", "pre-running", "smart", "smart-running"} {
+		os.Mkdir(filepath.Join(tmp, name), 0755)
+	}
+
+	existing := []*Session{
+		{Name: "scratch", Dir: "/tmp", Exists: true},
+		{Name: "pre-running", Dir: filepath.Join(tmp, "pre-running"), Exists: true},
+		{Name: "smart-running", Dir: filepath.Join(tmp, "smart-running"), Exists: true},
+	}
+	pre := []PreDefinedSession{
+		{Name: "pre", Dir: filepath.Join(tmp, "pre")},
+		{Name: "pre-running", Dir: filepath.Join(tmp, "pre-running")},
+	}
+	smart := []SmartDirectory{{Dir: tmp, Ignore: []string{"pre*"}}}
+
+	finder := NewFinder(&mockTmuxRepository{allSessions: existing}, pre, smart, nil)
+
+	want := map[string]Source{
+		"scratch":       SourceTmux,
+		"pre-running":   SourcePreDefined,
+		"smart-running": SourceSmartDirectory,
+		"pre":           SourcePreDefined,
+		"smart":         SourceSmartDirectory,
+	}
+	got := make(map[string]Source)
+	for _, s := range finder.List(false) {
+		got[s.Name] = s.Source
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("List(false) sources = %v, want %v", got, want)
+	}
+
+	for _, s := range finder.List(false) {
+		if wantRoot := s.Source == SourceSmartDirectory; (s.Root == tmp) != wantRoot {
+			t.Errorf("%s: unexpected Root %q", s.Name, s.Root)
+		}
+	}
+
+	for name, source := range map[string]Source{"pre": SourcePreDefined, "smart": SourceSmartDirectory} {
+		s, err := finder.Find(name)
+		if err != nil || s == nil {
+			t.Fatalf("Find(%q) = %v, %v", name, s, err)
+		}
+		if s.Source != source {
+			t.Errorf("Find(%q).Source = %q, want %q", name, s.Source, source)
+		}
+	}
+	if s, _ := finder.Find("smart"); s.Root != tmp {
+		t.Errorf("expected Root %q, got %q", tmp, s.Root)
+	}
+
+	running := NewFinder(&mockTmuxRepository{hasSession: true}, nil, nil, nil)
+	if s, _ := running.Find("scratch"); s == nil || s.Source != SourceTmux {
+		t.Errorf("expected running session to have source %q, got %+v", SourceTmux, s)
+	}
+}
+
+func TestList_Socket(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{"api", "web"} {
+		os.Mkdir(filepath.Join(tmp, name), 0755)
+	}
+
+	existing := []*Session{
+		{Name: "api", Dir: filepath.Join(tmp, "api"), Exists: true},
+		{Name: "web", Dir: filepath.Join(tmp, "web"), Exists: true, Socket: "work"},
+	}
+	pre := []PreDefinedSession{
+		{Name: "api", Dir: filepath.Join(tmp, "api"), Socket: "work", Aliases: []string{"a"}},
+	}
+	smart := []SmartDirectory{{Dir: tmp}}
+
+	finder := NewFinder(&mockTmuxRepository{allSessions: existing}, pre, smart, nil)
+
+	type entry struct {
+		name, socket string
+		exists       bool
+		source       Source
+	}
+	var got []entry
+	for _, s := range finder.List(false) {
+		got = append(got, entry{s.Name, s.Socket, s.Exists, s.Source})
+	}
+	want := []entry{
+		{"api", "", true, SourceSmartDirectory},
+		{"web", "work", true, SourceTmux},
+		{"api", "work", false, SourcePreDefined},
+		{"web", "", false, SourceSmartDirectory},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("List(false) = %v, want %v", got, want)
+	}
+}
+
 func TestList_PredefinedSmartNameCollision(t *testing.T) {
 	tmp := t.TempDir()
 	projectDir := filepath.Join(tmp, "collision")
@@ -526,7 +1002,7 @@ func TestList_PredefinedSmartNameCollision(t *testing.T) {
 
 	smart := []SmartDirectory{{Dir: tmp}}
 
-	finder := NewFinder(&mockTmuxRepository{}, pre, smart)
+	finder := NewFinder(&mockTmuxRepository{}, pre, smart, nil)
 	got := finder.List(false)
 
 	if len(got) != 1 {
@@ -600,7 +1076,7 @@ func TestFindPreDefinedSession_ExpandHomeDirError(t *testing.T) {
 	pre := []PreDefinedSession{
 		{Name: "myapp", Dir: "~/projects/myapp"},
 	}
-	finder := NewFinder(&mockTmuxRepository{}, pre, nil)
+	finder := NewFinder(&mockTmuxRepository{}, pre, nil, nil)
 	_, err := finder.findPreDefinedSession("myapp")
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -609,7 +1085,7 @@ func TestFindPreDefinedSession_ExpandHomeDirError(t *testing.T) {
 
 func TestFindSmartSessionDirectorySession_ExpandHomeDirError(t *testing.T) {
 	t.Setenv("HOME", "")
-	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: "~/projects"}})
+	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: "~/projects"}}, nil)
 	_, err := finder.findSmartSessionDirectorySession("myapp")
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -621,7 +1097,7 @@ func TestFind_PreDefinedExpandHomeDirError(t *testing.T) {
 	pre := []PreDefinedSession{
 		{Name: "myapp", Dir: "~/projects/myapp"},
 	}
-	finder := NewFinder(&mockTmuxRepository{}, pre, nil)
+	finder := NewFinder(&mockTmuxRepository{}, pre, nil, nil)
 	_, err := finder.Find("myapp")
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -630,7 +1106,7 @@ func TestFind_PreDefinedExpandHomeDirError(t *testing.T) {
 
 func TestFind_SmartDirectoryExpandHomeDirError(t *testing.T) {
 	t.Setenv("HOME", "")
-	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: "~/projects"}})
+	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: "~/projects"}}, nil)
 	_, err := finder.Find("myapp")
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -646,7 +1122,7 @@ func TestGetAllPreDefinedSessions_ExpandHomeDirError(t *testing.T) {
 		{Name: "bad", Dir: "~/bad"},
 		{Name: "good", Dir: validDir},
 	}
-	finder := NewFinder(&mockTmuxRepository{}, pre, nil)
+	finder := NewFinder(&mockTmuxRepository{}, pre, nil, nil)
 
 	t.Setenv("HOME", "")
 	got := finder.getAllPreDefinedSessions()
@@ -664,7 +1140,7 @@ func TestGetAllSmartSessionDirectorySessions_ExpandHomeDirError(t *testing.T) {
 	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{
 		{Dir: "~/bad"},
 		{Dir: tmp},
-	})
+	}, nil)
 
 	t.Setenv("HOME", "")
 	got := finder.getAllSmartSessionDirectorySessions()
@@ -685,7 +1161,7 @@ func TestGetAllSmartSessionDirectorySessions_ReadDirError(t *testing.T) {
 	}
 	defer os.Chmod(tmp, 0755)
 
-	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: tmp}})
+	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: tmp}}, nil)
 	got := finder.getAllSmartSessionDirectorySessions()
 	if len(got) != 0 {
 		t.Errorf("expected empty result, got %v", got)
@@ -697,7 +1173,7 @@ func TestGetAllSmartSessionDirectorySessions_SkipsNonDirectories(t *testing.T) {
 	os.Mkdir(filepath.Join(tmp, "subdir"), 0755)
 	os.WriteFile(filepath.Join(tmp, "file.txt"), []byte(""), 0644)
 
-	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: tmp}})
+	finder := NewFinder(&mockTmuxRepository{}, nil, []SmartDirectory{{Dir: tmp}}, nil)
 	got := finder.getAllSmartSessionDirectorySessions()
 
 	if len(got) != 1 || got[0].Name != "subdir" {
@@ -0,0 +1,10 @@
+// Package shell quotes arguments for the commands tm hands to sh: hooks,
+// fzf previews and tmux popups.
+package shell
+
+import "strings"
+
+// Quote quotes s for sh so it is read back as a single word.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,25 @@
+package shell
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "plain", s: "work", want: "'work'"},
+		{name: "empty", s: "", want: "''"},
+		{name: "spaces", s: "/home/me/my dir", want: "'/home/me/my dir'"},
+		{name: "single quote", s: "it's", want: `'it'\''s'`},
+		{name: "shell syntax", s: "$HOME; rm -rf `x`", want: "'$HOME; rm -rf `x`'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Quote(tt.s); got != tt.want {
+				t.Errorf("Quote(%q) = %s, want %s", tt.s, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,17 +1,26 @@
 package tmux
 
 import (
+	"fmt"
+	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/griggsjared/tm/internal/session"
+	"github.com/griggsjared/tm/internal/shell"
 )
 
 const listSessionsFormat = "#{session_name}\t#{session_path}\t#{session_last_attached}"
 
+const newPaneFormat = "#{window_id}\t#{pane_id}"
+
+const listWindowsFormat = "#{window_index}: #{window_name}#{?window_active, (active),} [#{window_panes} panes]"
+
 type Runner interface {
 	Output(path string, args []string) ([]byte, error)
 	Exec(path string, args []string) error
@@ -31,15 +40,58 @@ func (t *TmuxRunner) Exec(path string, args []string) error {
 	return syscall.Exec(path, args, os.Environ())
 }
 
+// Socket selects a tmux server, by socket name like tmux -L or by socket
+// path like tmux -S. The zero Socket is the default server.
+type Socket struct {
+	Name string
+	Path string
+}
+
+// ParseSocket reads a socket as it is written in the config file or
+// TM_TMUX_SOCKET: a path when it contains a slash, a name otherwise.
+func ParseSocket(s string) Socket {
+	if strings.Contains(s, "/") {
+		return Socket{Path: s}
+	}
+	return Socket{Name: s}
+}
+
+// String returns the socket as ParseSocket reads it, or an empty string for
+// the default server.
+func (s Socket) String() string {
+	if s.Path != "" {
+		return s.Path
+	}
+	return s.Name
+}
+
+func (s Socket) args() []string {
+	switch {
+	case s.Path != "":
+		return []string{"-S", s.Path}
+	case s.Name != "":
+		return []string{"-L", s.Name}
+	}
+	return nil
+}
+
 type Client struct {
-	runner Runner
-	path   string
+	runner  Runner
+	path    string
+	socket  Socket
+	servers []string
 }
 
-func NewClient(r Runner, path string) *Client {
+// NewClient creates a new Client with the given Runner and tmux path. socket
+// selects the tmux server the client talks to. Sessions on the servers
+// listed in servers are listed too, each with its Socket set, and commands
+// about them go to their own server.
+func NewClient(r Runner, path string, socket Socket, servers []string) *Client {
 	return &Client{
-		runner: r,
-		path:   path,
+		runner:  r,
+		path:    path,
+		socket:  socket,
+		servers: servers,
 	}
 }
 
@@ -55,8 +107,29 @@ func (c *Client) Path() string {
 	return c.path
 }
 
+func (c *Client) Socket() Socket {
+	return c.socket
+}
+
+// on returns a client for the server s lives on.
+func (c *Client) on(s *session.Session) *Client {
+	return c.onSocket(s.Socket)
+}
+
+// onSocket returns a client for the server socket names, or c itself for an
+// empty socket.
+func (c *Client) onSocket(socket string) *Client {
+	if socket == "" {
+		return c
+	}
+	other := *c
+	other.socket = ParseSocket(socket)
+	other.servers = nil
+	return &other
+}
+
 func (c *Client) Version() string {
-	output, err := c.runner.Output(c.path, []string{"-V"})
+	output, err := c.output([]string{"-V"})
 	if err != nil {
 		return ""
 	}
@@ -67,37 +140,250 @@ func (c *Client) Version() string {
 	return parts[1]
 }
 
-func (c *Client) HasSession(name string) bool {
-	if _, err := c.runner.Output(c.path, []string{"has-session", "-t", "=" + name}); err != nil {
+// HasSession reports whether the session is running on the server socket
+// names, or on the client's own server when socket is empty.
+func (c *Client) HasSession(name, socket string) bool {
+	if _, err := c.onSocket(socket).output([]string{"has-session", "-t", "=" + name}); err != nil {
 		return false
 	}
 	return true
 }
 
 func (c *Client) NewSession(s *session.Session) error {
-	_, err := c.runner.Output(c.path, []string{"new-session", "-d", "-s", s.Name, "-c", s.Dir})
+	c = c.on(s)
+	if len(s.Windows) == 0 {
+		args := append([]string{"new-session", "-d", "-s", s.Name, "-c", s.Dir}, envArgs(s.Env)...)
+		_, err := c.output(args)
+		return err
+	}
+
+	var started []startedPane
+	for i, w := range s.Windows {
+		panes := windowPanes(w)
+
+		var args []string
+		if i == 0 {
+			args = append([]string{"new-session", "-d", "-s", s.Name}, envArgs(s.Env)...)
+		} else {
+			args = []string{"new-window", "-d", "-t", "=" + s.Name + ":"}
+		}
+		args = append(args, "-c", panes[0].Dir, "-P", "-F", newPaneFormat)
+		if w.Name != "" {
+			args = append(args, "-n", w.Name)
+		}
+
+		output, err := c.output(args)
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+			return fmt.Errorf("failed to create window %q: %w", w.Name, err)
+		}
+
+		windowID, paneID, _ := strings.Cut(strings.TrimSpace(string(output)), "\t")
+		paneIDs, err := c.buildWindow(windowID, paneID, w, panes)
+		if err != nil {
+			return err
+		}
+		for j, id := range paneIDs {
+			started = append(started, startedPane{id: id, pane: panes[j]})
+		}
+	}
+
+	// Commands are only sent once every pane exists, so a command that
+	// inspects the window size or layout sees the final arrangement.
+	for _, p := range started {
+		if err := c.sendCommand(p.id, p.pane); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type startedPane struct {
+	id   string
+	pane session.Pane
+}
+
+// buildWindow splits the window into the given panes and applies its layout,
+// returning the pane ids in order. Each split targets the previously created
+// pane so the panes end up in the order they were configured.
+func (c *Client) buildWindow(windowID, paneID string, w session.Window, panes []session.Pane) ([]string, error) {
+	paneIDs := []string{paneID}
+	for i := 1; i < len(panes); i++ {
+		args := []string{"split-window", "-d", "-t", paneID, "-c", panes[i].Dir, "-P", "-F", "#{pane_id}"}
+		output, err := c.output(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split window %q: %w", w.Name, err)
+		}
+		paneID = strings.TrimSpace(string(output))
+		paneIDs = append(paneIDs, paneID)
+	}
+
+	if w.Layout != "" {
+		if _, err := c.output([]string{"select-layout", "-t", windowID, w.Layout}); err != nil {
+			return nil, fmt.Errorf("failed to apply layout %q to window %q: %w", w.Layout, w.Name, err)
+		}
+	}
+	return paneIDs, nil
+}
+
+// sendCommand types the pane command literally and presses Enter unless the
+// pane asks for the command to only be typed.
+func (c *Client) sendCommand(paneID string, p session.Pane) error {
+	if p.Command == "" {
+		return nil
+	}
+	if _, err := c.output([]string{"send-keys", "-t", paneID, "-l", p.Command}); err != nil {
+		return fmt.Errorf("failed to send command %q: %w", p.Command, err)
+	}
+	if p.NoEnter {
+		return nil
+	}
+	if _, err := c.output([]string{"send-keys", "-t", paneID, "Enter"}); err != nil {
+		return fmt.Errorf("failed to send command %q: %w", p.Command, err)
+	}
+	return nil
+}
+
+func (c *Client) KillSession(s *session.Session) error {
+	c = c.on(s)
+	_, err := c.output([]string{"kill-session", "-t", "=" + s.Name})
+	return err
+}
+
+func (c *Client) RenameSession(s *session.Session, name string) error {
+	c = c.on(s)
+	_, err := c.output([]string{"rename-session", "-t", "=" + s.Name, name})
+	return err
+}
+
+// NewWindow opens a window called name in dir at the end of session s.
+func (c *Client) NewWindow(s *session.Session, name, dir string) error {
+	c = c.on(s)
+	_, err := c.output([]string{"new-window", "-t", "=" + s.Name + ":", "-n", name, "-c", dir})
+	return err
+}
+
+// DisplayPopup runs command in a popup over the current client, closing it
+// when the command exits. Empty width, height and border use tmux's defaults.
+func (c *Client) DisplayPopup(width, height, border, dir, command string) error {
+	args := []string{"display-popup", "-E"}
+	if width != "" {
+		args = append(args, "-w", width)
+	}
+	if height != "" {
+		args = append(args, "-h", height)
+	}
+	if border != "" {
+		args = append(args, "-b", border)
+	}
+	if dir != "" {
+		args = append(args, "-d", dir)
+	}
+	args = append(args, command)
+
+	_, err := c.output(args)
+	return err
+}
+
+// AddHook appends a tmux hook on the session for event that runs the shell
+// command in the background with run-shell.
+func (c *Client) AddHook(s *session.Session, event, command string) error {
+	c = c.on(s)
+	_, err := c.output([]string{"set-hook", "-a", "-t", "=" + s.Name + ":", event, "run-shell -b " + quoteCommandArg(command)})
 	return err
 }
 
 func (c *Client) AttachSession(s *session.Session) error {
-	return c.runner.Exec(c.path, []string{"tmux", "attach-session", "-t", s.Name})
+	c = c.on(s)
+	return c.exec([]string{"attach-session", "-t", s.Name})
 }
 
+// SwitchSession moves the current client to the session. A client cannot
+// switch to another server, so for a session elsewhere the client detaches
+// and attaches to that server instead.
 func (c *Client) SwitchSession(s *session.Session) error {
-	return c.runner.Exec(c.path, []string{"tmux", "switch-client", "-t", s.Name})
+	target := c.on(s)
+	if c.isCurrentServer(target.socket) {
+		return target.exec([]string{"switch-client", "-t", s.Name})
+	}
+
+	attach := append([]string{c.path}, target.socket.args()...)
+	attach = append(attach, "attach-session", "-t", "="+s.Name)
+	quoted := make([]string, len(attach))
+	for i, arg := range attach {
+		quoted[i] = shell.Quote(arg)
+	}
+	// Without -L or -S tmux talks to the server in TMUX, the current one.
+	_, err := c.runner.Output(c.path, []string{"detach-client", "-E", strings.Join(quoted, " ")})
+	return err
+}
+
+// isCurrentServer reports whether socket is the server of the tmux client tm
+// runs in, going by the socket path in TMUX. Outside tmux every server
+// counts as current.
+func (c *Client) isCurrentServer(socket Socket) bool {
+	current, _, _ := strings.Cut(os.Getenv("TMUX"), ",")
+	if current == "" {
+		return true
+	}
+	switch {
+	case socket.Path != "":
+		return filepath.Clean(socket.Path) == filepath.Clean(current)
+	case socket.Name != "":
+		return filepath.Base(current) == socket.Name
+	}
+	return filepath.Base(current) == "default"
+}
+
+// ListWindows returns one line per window of the session, with its index,
+// name and number of panes.
+func (c *Client) ListWindows(s *session.Session) (string, error) {
+	c = c.on(s)
+	output, err := c.output([]string{"list-windows", "-t", "=" + s.Name, "-F", listWindowsFormat})
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// CapturePane returns the visible contents of the active pane of the
+// session, with colours, and without the empty lines below the last output.
+func (c *Client) CapturePane(s *session.Session) (string, error) {
+	c = c.on(s)
+	output, err := c.output([]string{"capture-pane", "-p", "-e", "-t", "=" + s.Name + ":"})
+	if err != nil {
+		return "", err
+	}
+	pane := strings.TrimRight(string(output), " \n")
+	if pane == "" {
+		return "", nil
+	}
+	return pane + "\n", nil
 }
 
 func (c *Client) CurrentSession() string {
-	output, err := c.runner.Output(c.path, []string{"display-message", "-p", "#S"})
+	output, err := c.output([]string{"display-message", "-p", "#S"})
 	if err != nil {
 		return ""
 	}
 	return strings.TrimSpace(string(output))
 }
 
+// AllSessions lists the sessions on the client's server and then on each of
+// its other servers, whose sessions have their Socket set.
 func (c *Client) AllSessions() []*session.Session {
+	sessions := c.listSessions("")
+	for _, server := range c.servers {
+		sessions = append(sessions, c.onSocket(server).listSessions(server)...)
+	}
+	return sessions
+}
+
+func (c *Client) listSessions(socket string) []*session.Session {
 	var sessions []*session.Session
-	output, err := c.runner.Output(c.path, []string{"list-sessions", "-F", listSessionsFormat})
+	output, err := c.output([]string{"list-sessions", "-F", listSessionsFormat})
 	if err != nil {
 		return sessions
 	}
@@ -117,7 +403,59 @@ func (c *Client) AllSessions() []*session.Session {
 		if err != nil {
 			lastAttached = 0
 		}
-		sessions = append(sessions, session.New(parts[0], parts[1], true, lastAttached))
+		s := session.New(parts[0], parts[1], true, lastAttached)
+		s.Socket = socket
+		sessions = append(sessions, s)
 	}
 	return sessions
 }
+
+// envArgs turns env into new-session -e flags, sorted by key so the command
+// is stable. Variables set this way live in the session environment, so
+// every window created in the session inherits them.
+func envArgs(env map[string]string) []string {
+	var args []string
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		args = append(args, "-e", k+"="+env[k])
+	}
+	return args
+}
+
+// windowPanes returns the panes to build for w. A window without panes gets a
+// single pane in the window directory, and the window command runs in the
+// first pane unless that pane has a command of its own.
+func windowPanes(w session.Window) []session.Pane {
+	panes := slices.Clone(w.Panes)
+	if len(panes) == 0 {
+		panes = []session.Pane{{Dir: w.Dir}}
+	}
+	if panes[0].Dir == "" {
+		panes[0].Dir = w.Dir
+	}
+	if panes[0].Command == "" {
+		panes[0].Command = w.Command
+		panes[0].NoEnter = w.NoEnter
+	}
+	return panes
+}
+
+// quoteCommandArg quotes s as a double-quoted argument for the tmux command
+// parser, escaping the characters tmux would otherwise interpret. # is
+// doubled because run-shell expands formats such as #{session_name} and #S
+// in the command it runs.
+func quoteCommandArg(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, `#`, `##`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func (c *Client) output(args []string) ([]byte, error) {
+	return c.runner.Output(c.path, c.withSocket(args))
+}
+
+func (c *Client) exec(args []string) error {
+	return c.runner.Exec(c.path, append([]string{"tmux"}, c.withSocket(args)...))
+}
+
+func (c *Client) withSocket(args []string) []string {
+	return append(c.socket.args(), args...)
+}
@@ -2,6 +2,7 @@ package tmux
 
 import (
 	"errors"
+	"slices"
 	"testing"
 
 	"github.com/griggsjared/tm/internal/session"
@@ -29,6 +30,33 @@ func (t *TestRunner) Exec(path string, args []string) error {
 	return t.error
 }
 
+// SequenceRunner records every call and replies with the queued outputs in
+// order, for client methods that issue several tmux commands.
+type SequenceRunner struct {
+	outputs [][]byte
+	errors  []error
+	calls   [][]string
+}
+
+func (s *SequenceRunner) Output(path string, args []string) ([]byte, error) {
+	i := len(s.calls)
+	s.calls = append(s.calls, args)
+	var output []byte
+	if i < len(s.outputs) {
+		output = s.outputs[i]
+	}
+	var err error
+	if i < len(s.errors) {
+		err = s.errors[i]
+	}
+	return output, err
+}
+
+func (s *SequenceRunner) Exec(path string, args []string) error {
+	s.calls = append(s.calls, args)
+	return nil
+}
+
 func TestNewRunner(t *testing.T) {
 	runner := NewRunner()
 	if runner == nil {
@@ -57,7 +85,7 @@ func TestClient_IsAvailable(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cr := &TestRunner{}
-			client := NewClient(cr, tt.path)
+			client := NewClient(cr, tt.path, Socket{}, nil)
 
 			got := client.IsAvailable()
 			if got != tt.wantAvail {
@@ -88,7 +116,7 @@ func TestClient_Path(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cr := &TestRunner{}
-			client := NewClient(cr, tt.path)
+			client := NewClient(cr, tt.path, Socket{}, nil)
 
 			got := client.Path()
 			if got != tt.wantPath {
@@ -125,13 +153,13 @@ func TestClient_HasSession(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cr := &TestRunner{error: tt.wantErr}
-			client := NewClient(cr, "/usr/bin/tmux")
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
 
 			var exists bool
 			if tt.name == "session exists" {
-				exists = client.HasSession("test-session")
+				exists = client.HasSession("test-session", "")
 			} else {
-				exists = client.HasSession("non-existent")
+				exists = client.HasSession("non-existent", "")
 			}
 
 			if exists != tt.wantExists {
@@ -178,7 +206,7 @@ func TestClient_NewSession(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cr := &TestRunner{error: tt.wantErr}
-			client := NewClient(cr, "/usr/bin/tmux")
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
 
 			err := client.NewSession(tt.sess)
 
@@ -200,6 +228,501 @@ func TestClient_NewSession(t *testing.T) {
 	}
 }
 
+func TestClient_NewSession_Windows(t *testing.T) {
+	sess := &session.Session{
+		Name: "proj",
+		Dir:  "/src/proj",
+		Windows: []session.Window{
+			{
+				Name:   "editor",
+				Dir:    "/src/proj",
+				Layout: "main-vertical",
+				Panes: []session.Pane{
+					{Dir: "/src/proj"},
+					{Dir: "/src/proj/api"},
+					{Dir: "/src/proj/web"},
+				},
+			},
+			{Name: "logs", Dir: "/var/log"},
+		},
+	}
+
+	t.Run("builds windows, panes and layouts", func(t *testing.T) {
+		sr := &SequenceRunner{outputs: [][]byte{
+			[]byte("@1\t%1\n"),
+			[]byte("%2\n"),
+			[]byte("%3\n"),
+			nil,
+			[]byte("@2\t%4\n"),
+		}}
+		client := NewClient(sr, "/usr/bin/tmux", Socket{}, nil)
+
+		if err := client.NewSession(sess); err != nil {
+			t.Fatalf("NewSession error = %v", err)
+		}
+
+		want := [][]string{
+			{"new-session", "-d", "-s", "proj", "-c", "/src/proj", "-P", "-F", "#{window_id}\t#{pane_id}", "-n", "editor"},
+			{"split-window", "-d", "-t", "%1", "-c", "/src/proj/api", "-P", "-F", "#{pane_id}"},
+			{"split-window", "-d", "-t", "%2", "-c", "/src/proj/web", "-P", "-F", "#{pane_id}"},
+			{"select-layout", "-t", "@1", "main-vertical"},
+			{"new-window", "-d", "-t", "=proj:", "-c", "/var/log", "-P", "-F", "#{window_id}\t#{pane_id}", "-n", "logs"},
+		}
+		if len(sr.calls) != len(want) {
+			t.Fatalf("expected %d calls, got %d: %v", len(want), len(sr.calls), sr.calls)
+		}
+		for i := range want {
+			if !slices.Equal(sr.calls[i], want[i]) {
+				t.Errorf("call %d = %v, want %v", i, sr.calls[i], want[i])
+			}
+		}
+	})
+
+	t.Run("new-session error is returned", func(t *testing.T) {
+		wantErr := errors.New("duplicate session")
+		sr := &SequenceRunner{errors: []error{wantErr}}
+		client := NewClient(sr, "/usr/bin/tmux", Socket{}, nil)
+
+		if err := client.NewSession(sess); err != wantErr {
+			t.Fatalf("NewSession error = %v, want %v", err, wantErr)
+		}
+		if len(sr.calls) != 1 {
+			t.Errorf("expected 1 call, got %d", len(sr.calls))
+		}
+	})
+
+	t.Run("split error stops building", func(t *testing.T) {
+		sr := &SequenceRunner{
+			outputs: [][]byte{[]byte("@1\t%1\n")},
+			errors:  []error{nil, errors.New("no space for new pane")},
+		}
+		client := NewClient(sr, "/usr/bin/tmux", Socket{}, nil)
+
+		err := client.NewSession(sess)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !errors.Is(err, sr.errors[1]) {
+			t.Errorf("expected wrapped split error, got %v", err)
+		}
+		if len(sr.calls) != 2 {
+			t.Errorf("expected 2 calls, got %d", len(sr.calls))
+		}
+	})
+
+	t.Run("layout error is returned", func(t *testing.T) {
+		sr := &SequenceRunner{
+			outputs: [][]byte{[]byte("@1\t%1\n")},
+			errors:  []error{nil, errors.New("invalid layout")},
+		}
+		client := NewClient(sr, "/usr/bin/tmux", Socket{}, nil)
+
+		err := client.NewSession(&session.Session{
+			Name:    "proj",
+			Dir:     "/src/proj",
+			Windows: []session.Window{{Name: "main", Dir: "/src/proj", Layout: "bogus"}},
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !slices.Equal(sr.calls[1], []string{"select-layout", "-t", "@1", "bogus"}) {
+			t.Errorf("unexpected layout call %v", sr.calls[1])
+		}
+	})
+}
+
+func TestClient_NewSession_Commands(t *testing.T) {
+	sess := &session.Session{
+		Name: "proj",
+		Dir:  "/src/proj",
+		Windows: []session.Window{
+			{
+				Name: "editor",
+				Dir:  "/src/proj",
+				Panes: []session.Pane{
+					{Dir: "/src/proj", Command: "nvim ."},
+					{Dir: "/src/proj", Command: "go test ./... -watch"},
+					{Dir: "/src/proj", Command: "rm -rf tmp", NoEnter: true},
+				},
+			},
+			{Name: "services", Dir: "/src/proj", Command: "docker compose up"},
+		},
+	}
+
+	sr := &SequenceRunner{outputs: [][]byte{
+		[]byte("@1\t%1\n"),
+		[]byte("%2\n"),
+		[]byte("%3\n"),
+		[]byte("@2\t%4\n"),
+	}}
+	client := NewClient(sr, "/usr/bin/tmux", Socket{}, nil)
+
+	if err := client.NewSession(sess); err != nil {
+		t.Fatalf("NewSession error = %v", err)
+	}
+
+	want := [][]string{
+		{"send-keys", "-t", "%1", "-l", "nvim ."},
+		{"send-keys", "-t", "%1", "Enter"},
+		{"send-keys", "-t", "%2", "-l", "go test ./... -watch"},
+		{"send-keys", "-t", "%2", "Enter"},
+		{"send-keys", "-t", "%3", "-l", "rm -rf tmp"},
+		{"send-keys", "-t", "%4", "-l", "docker compose up"},
+		{"send-keys", "-t", "%4", "Enter"},
+	}
+	// The first four calls build the windows; keys are only sent afterwards.
+	got := sr.calls[4:]
+	if len(got) != len(want) {
+		t.Fatalf("expected %d send-keys calls, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("call %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	t.Run("send-keys error is returned", func(t *testing.T) {
+		sr := &SequenceRunner{
+			outputs: [][]byte{[]byte("@1\t%1\n")},
+			errors:  []error{nil, errors.New("can't find pane")},
+		}
+		client := NewClient(sr, "/usr/bin/tmux", Socket{}, nil)
+
+		err := client.NewSession(&session.Session{
+			Name:    "proj",
+			Dir:     "/src/proj",
+			Windows: []session.Window{{Dir: "/src/proj", Command: "make"}},
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !errors.Is(err, sr.errors[1]) {
+			t.Errorf("expected wrapped send-keys error, got %v", err)
+		}
+	})
+}
+
+func TestWindowPanes(t *testing.T) {
+	t.Run("window without panes gets one pane", func(t *testing.T) {
+		got := windowPanes(session.Window{Dir: "/src", Command: "make", NoEnter: true})
+		want := []session.Pane{{Dir: "/src", Command: "make", NoEnter: true}}
+		if !slices.Equal(got, want) {
+			t.Errorf("windowPanes() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("pane command wins over window command", func(t *testing.T) {
+		w := session.Window{Dir: "/src", Command: "make", Panes: []session.Pane{{Dir: "/src/a", Command: "ls"}}}
+		got := windowPanes(w)
+		if got[0].Command != "ls" {
+			t.Errorf("expected pane command ls, got %s", got[0].Command)
+		}
+		if w.Panes[0].Command != "ls" {
+			t.Error("expected window panes to be left untouched")
+		}
+	})
+
+	t.Run("window command fills the first pane", func(t *testing.T) {
+		got := windowPanes(session.Window{Dir: "/src", Command: "make", Panes: []session.Pane{{Dir: "/src/a"}, {Dir: "/src/b"}}})
+		if got[0].Command != "make" || got[1].Command != "" {
+			t.Errorf("unexpected panes %v", got)
+		}
+	})
+}
+
+func TestClient_NewSession_Env(t *testing.T) {
+	env := map[string]string{"KUBECONFIG": "/home/me/.kube/work", "AWS_PROFILE": "work"}
+
+	t.Run("single window", func(t *testing.T) {
+		cr := &TestRunner{}
+		client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
+
+		if err := client.NewSession(&session.Session{Name: "proj", Dir: "/src/proj", Env: env}); err != nil {
+			t.Fatalf("NewSession error = %v", err)
+		}
+		want := []string{"new-session", "-d", "-s", "proj", "-c", "/src/proj", "-e", "AWS_PROFILE=work", "-e", "KUBECONFIG=/home/me/.kube/work"}
+		if !slices.Equal(cr.providedArgs, want) {
+			t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+		}
+	})
+
+	t.Run("env is only set on the session", func(t *testing.T) {
+		sr := &SequenceRunner{outputs: [][]byte{[]byte("@1\t%1\n"), []byte("@2\t%2\n")}}
+		client := NewClient(sr, "/usr/bin/tmux", Socket{}, nil)
+
+		err := client.NewSession(&session.Session{
+			Name:    "proj",
+			Dir:     "/src/proj",
+			Env:     map[string]string{"AWS_PROFILE": "work"},
+			Windows: []session.Window{{Dir: "/src/proj"}, {Dir: "/src/proj"}},
+		})
+		if err != nil {
+			t.Fatalf("NewSession error = %v", err)
+		}
+		if !slices.Contains(sr.calls[0], "AWS_PROFILE=work") {
+			t.Errorf("expected new-session to carry env, got %v", sr.calls[0])
+		}
+		if slices.Contains(sr.calls[1], "-e") {
+			t.Errorf("expected new-window to inherit env, got %v", sr.calls[1])
+		}
+	})
+}
+
+func TestClient_KillSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{name: "successful kill"},
+		{name: "failed kill", wantErr: errors.New("no such session")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &TestRunner{error: tt.wantErr}
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
+
+			err := client.KillSession(&session.Session{Name: "old"})
+
+			if err != tt.wantErr {
+				t.Fatalf("KillSession error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if want := []string{"kill-session", "-t", "=old"}; !slices.Equal(cr.providedArgs, want) {
+				t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+			}
+		})
+	}
+}
+
+func TestClient_RenameSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{name: "successful rename"},
+		{name: "failed rename", wantErr: errors.New("duplicate session: new")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &TestRunner{error: tt.wantErr}
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
+
+			err := client.RenameSession(&session.Session{Name: "old"}, "new")
+
+			if err != tt.wantErr {
+				t.Fatalf("RenameSession error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if want := []string{"rename-session", "-t", "=old", "new"}; !slices.Equal(cr.providedArgs, want) {
+				t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+			}
+		})
+	}
+}
+
+func TestClient_NewWindow(t *testing.T) {
+	cr := &TestRunner{}
+	client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
+
+	if err := client.NewWindow(&session.Session{Name: "work"}, "api", "/src/api"); err != nil {
+		t.Fatalf("NewWindow error = %v", err)
+	}
+
+	want := []string{"new-window", "-t", "=work:", "-n", "api", "-c", "/src/api"}
+	if !slices.Equal(cr.providedArgs, want) {
+		t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+	}
+}
+
+func TestClient_DisplayPopup(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  string
+		height string
+		border string
+		dir    string
+		want   []string
+	}{
+		{
+			name: "tmux defaults",
+			want: []string{"display-popup", "-E", "tm"},
+		},
+		{
+			name:   "size, border and directory",
+			width:  "80%",
+			height: "20",
+			border: "rounded",
+			dir:    "/src/api",
+			want:   []string{"display-popup", "-E", "-w", "80%", "-h", "20", "-b", "rounded", "-d", "/src/api", "tm"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &TestRunner{}
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
+
+			if err := client.DisplayPopup(tt.width, tt.height, tt.border, tt.dir, "tm"); err != nil {
+				t.Fatalf("DisplayPopup error = %v", err)
+			}
+			if !slices.Equal(cr.providedArgs, tt.want) {
+				t.Errorf("Expected args %v, got %v", tt.want, cr.providedArgs)
+			}
+		})
+	}
+}
+
+func TestClient_AddHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{
+			name:    "quotes, variables and backslashes",
+			command: `echo "$HOME" \ done`,
+			want:    `run-shell -b "echo \"\$HOME\" \\ done"`,
+		},
+		{
+			name:    "formats are left to the shell",
+			command: `cd '/src/#{app}' && echo "#S" # done`,
+			want:    `run-shell -b "cd '/src/##{app}' && echo \"##S\" ## done"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &TestRunner{}
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
+
+			if err := client.AddHook(&session.Session{Name: "proj"}, "client-detached", tt.command); err != nil {
+				t.Fatalf("AddHook error = %v", err)
+			}
+
+			want := []string{"set-hook", "-a", "-t", "=proj:", "client-detached", tt.want}
+			if !slices.Equal(cr.providedArgs, want) {
+				t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+			}
+		})
+	}
+}
+
+func TestParseSocket(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Socket
+		args []string
+	}{
+		{in: "", want: Socket{}},
+		{in: "work", want: Socket{Name: "work"}, args: []string{"-L", "work"}},
+		{in: "/tmp/tmux-1000/work", want: Socket{Path: "/tmp/tmux-1000/work"}, args: []string{"-S", "/tmp/tmux-1000/work"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := ParseSocket(tt.in)
+			if got != tt.want {
+				t.Fatalf("ParseSocket(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if got.String() != tt.in {
+				t.Errorf("String() = %q, want %q", got.String(), tt.in)
+			}
+			if !slices.Equal(got.args(), tt.args) {
+				t.Errorf("args() = %v, want %v", got.args(), tt.args)
+			}
+		})
+	}
+}
+
+func TestClient_Socket(t *testing.T) {
+	cr := &TestRunner{}
+	client := NewClient(cr, "/usr/bin/tmux", Socket{Name: "work"}, nil)
+
+	if err := client.KillSession(&session.Session{Name: "proj"}); err != nil {
+		t.Fatalf("KillSession error = %v", err)
+	}
+	if want := []string{"-L", "work", "kill-session", "-t", "=proj"}; !slices.Equal(cr.providedArgs, want) {
+		t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+	}
+
+	if err := client.AttachSession(&session.Session{Name: "proj"}); err != nil {
+		t.Fatalf("AttachSession error = %v", err)
+	}
+	if want := []string{"tmux", "-L", "work", "attach-session", "-t", "proj"}; !slices.Equal(cr.providedArgs, want) {
+		t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+	}
+
+	path := NewClient(cr, "/usr/bin/tmux", Socket{Path: "/tmp/work.sock"}, nil)
+	path.HasSession("proj", "")
+	if want := []string{"-S", "/tmp/work.sock", "has-session", "-t", "=proj"}; !slices.Equal(cr.providedArgs, want) {
+		t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+	}
+
+	// A session on another server is handled there.
+	if err := client.KillSession(&session.Session{Name: "proj", Socket: "/tmp/other.sock"}); err != nil {
+		t.Fatalf("KillSession error = %v", err)
+	}
+	if want := []string{"-S", "/tmp/other.sock", "kill-session", "-t", "=proj"}; !slices.Equal(cr.providedArgs, want) {
+		t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+	}
+	client.HasSession("proj", "home")
+	if want := []string{"-L", "home", "has-session", "-t", "=proj"}; !slices.Equal(cr.providedArgs, want) {
+		t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+	}
+}
+
+func TestClient_SwitchSession_OtherServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		tmux     string
+		socket   Socket
+		sess     *session.Session
+		wantArgs []string
+	}{
+		{
+			name:     "same named server",
+			tmux:     "/tmp/tmux-1000/work,123,0",
+			socket:   Socket{Name: "work"},
+			sess:     &session.Session{Name: "proj"},
+			wantArgs: []string{"tmux", "-L", "work", "switch-client", "-t", "proj"},
+		},
+		{
+			name:     "default server",
+			tmux:     "/tmp/tmux-1000/default,123,0",
+			sess:     &session.Session{Name: "proj"},
+			wantArgs: []string{"tmux", "switch-client", "-t", "proj"},
+		},
+		{
+			name:     "session on another server",
+			tmux:     "/tmp/tmux-1000/default,123,0",
+			sess:     &session.Session{Name: "proj", Socket: "work"},
+			wantArgs: []string{"detach-client", "-E", "'/usr/bin/tmux' '-L' 'work' 'attach-session' '-t' '=proj'"},
+		},
+		{
+			name:     "client on another server",
+			tmux:     "/tmp/tmux-1000/default,123,0",
+			socket:   Socket{Path: "/tmp/it's.sock"},
+			sess:     &session.Session{Name: "proj"},
+			wantArgs: []string{"detach-client", "-E", `'/usr/bin/tmux' '-S' '/tmp/it'\''s.sock' 'attach-session' '-t' '=proj'`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMUX", tt.tmux)
+			cr := &TestRunner{}
+			client := NewClient(cr, "/usr/bin/tmux", tt.socket, nil)
+
+			if err := client.SwitchSession(tt.sess); err != nil {
+				t.Fatalf("SwitchSession error = %v", err)
+			}
+			if !slices.Equal(cr.providedArgs, tt.wantArgs) {
+				t.Errorf("Expected args %v, got %v", tt.wantArgs, cr.providedArgs)
+			}
+		})
+	}
+}
+
 func TestClient_AttachSession(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -233,7 +756,7 @@ func TestClient_AttachSession(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cr := &TestRunner{error: tt.wantErr}
-			client := NewClient(cr, "/usr/bin/tmux")
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
 
 			err := client.AttachSession(tt.sess)
 
@@ -286,8 +809,9 @@ func TestClient_SwitchSession(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMUX", "")
 			cr := &TestRunner{error: tt.wantErr}
-			client := NewClient(cr, "/usr/bin/tmux")
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
 
 			err := client.SwitchSession(tt.sess)
 
@@ -308,6 +832,53 @@ func TestClient_SwitchSession(t *testing.T) {
 	}
 }
 
+func TestClient_ListWindows(t *testing.T) {
+	cr := &TestRunner{output: []byte("0: editor (active) [2 panes]\n1: logs [1 panes]\n")}
+	client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
+
+	got, err := client.ListWindows(&session.Session{Name: "my proj"})
+	if err != nil {
+		t.Fatalf("ListWindows error = %v", err)
+	}
+	if got != "0: editor (active) [2 panes]\n1: logs [1 panes]\n" {
+		t.Errorf("unexpected windows %q", got)
+	}
+	if want := []string{"list-windows", "-t", "=my proj", "-F", listWindowsFormat}; !slices.Equal(cr.providedArgs, want) {
+		t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+	}
+
+	cr.error = errors.New("can't find session")
+	if _, err := client.ListWindows(&session.Session{Name: "gone"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestClient_CapturePane(t *testing.T) {
+	cr := &TestRunner{output: []byte("$ go test\nok\n\n\n   \n")}
+	client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
+
+	got, err := client.CapturePane(&session.Session{Name: "proj"})
+	if err != nil {
+		t.Fatalf("CapturePane error = %v", err)
+	}
+	if got != "$ go test\nok\n" {
+		t.Errorf("expected trailing blank lines to be trimmed, got %q", got)
+	}
+	if want := []string{"capture-pane", "-p", "-e", "-t", "=proj:"}; !slices.Equal(cr.providedArgs, want) {
+		t.Errorf("Expected args %v, got %v", want, cr.providedArgs)
+	}
+
+	cr.output = []byte("\n\n")
+	if got, _ := client.CapturePane(&session.Session{Name: "proj"}); got != "" {
+		t.Errorf("expected an empty pane to be empty, got %q", got)
+	}
+
+	cr.error = errors.New("can't find session")
+	if _, err := client.CapturePane(&session.Session{Name: "gone"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
 func TestClient_CurrentSession(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -343,7 +914,7 @@ func TestClient_CurrentSession(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cr := &TestRunner{output: tt.crOutput, error: tt.crError}
-			client := NewClient(cr, "/usr/bin/tmux")
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
 
 			got := client.CurrentSession()
 			if got != tt.wantResult {
@@ -412,7 +983,7 @@ func TestClient_Version(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cr := &TestRunner{output: tt.crOutput, error: tt.crError}
-			client := NewClient(cr, "/usr/bin/tmux")
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
 
 			got := client.Version()
 			if got != tt.wantResult {
@@ -457,7 +1028,7 @@ func TestClient_InsideTmux(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Setenv("TMUX", tt.tmuxEnv)
-			client := NewClient(&TestRunner{}, "/usr/bin/tmux")
+			client := NewClient(&TestRunner{}, "/usr/bin/tmux", Socket{}, nil)
 			got := client.InsideTmux()
 			if got != tt.wantBool {
 				t.Fatalf("InsideTmux() = %v, want %v", got, tt.wantBool)
@@ -534,7 +1105,7 @@ func TestClient_AllSessions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cr := &TestRunner{output: tt.crOutput, error: tt.crError}
-			client := NewClient(cr, "/usr/bin/tmux")
+			client := NewClient(cr, "/usr/bin/tmux", Socket{}, nil)
 
 			sessions := client.AllSessions()
 
@@ -562,3 +1133,33 @@ func TestClient_AllSessions(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_AllSessions_Servers(t *testing.T) {
+	sr := &SequenceRunner{
+		outputs: [][]byte{
+			[]byte("main\t/src/main\t3000\n"),
+			[]byte("api\t/src/api\t1000\n"),
+			nil,
+		},
+		errors: []error{nil, nil, errors.New("no server running")},
+	}
+	client := NewClient(sr, "/usr/bin/tmux", Socket{}, []string{"work", "/tmp/home.sock"})
+
+	type entry struct{ name, socket string }
+	var got []entry
+	for _, s := range client.AllSessions() {
+		got = append(got, entry{s.Name, s.Socket})
+	}
+	if want := []entry{{"main", ""}, {"api", "work"}}; !slices.Equal(got, want) {
+		t.Errorf("AllSessions() = %v, want %v", got, want)
+	}
+
+	wantCalls := [][]string{
+		{"list-sessions", "-F", listSessionsFormat},
+		{"-L", "work", "list-sessions", "-F", listSessionsFormat},
+		{"-S", "/tmp/home.sock", "list-sessions", "-F", listSessionsFormat},
+	}
+	if !slices.EqualFunc(sr.calls, wantCalls, slices.Equal) {
+		t.Errorf("Expected calls %v, got %v", wantCalls, sr.calls)
+	}
+}
@@ -0,0 +1,76 @@
+package trust
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/griggsjared/tm/internal/atomicfile"
+)
+
+// Store remembers whether the user trusted a project file, keyed by its path
+// and tied to a hash of its content so any edit asks again.
+type Store struct {
+	path string
+}
+
+type entry struct {
+	Hash    string `json:"hash"`
+	Trusted bool   `json:"trusted"`
+}
+
+func NewStore(path string) *Store {
+	return &Store{
+		path: path,
+	}
+}
+
+// Lookup reports the remembered answer for the file at path with the given
+// content hash. ok is false when the file was never answered or has changed.
+func (s *Store) Lookup(path, hash string) (trusted bool, ok bool) {
+	entries, err := s.load()
+	if err != nil {
+		return false, false
+	}
+	e, found := entries[path]
+	if !found || e.Hash != hash {
+		return false, false
+	}
+	return e.Trusted, true
+}
+
+func (s *Store) Save(path, hash string, trusted bool) error {
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	entries[path] = entry{Hash: hash, Trusted: trusted}
+
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return atomicfile.Write(s.path, content, 0600)
+}
+
+func (s *Store) load() (map[string]entry, error) {
+	entries := make(map[string]entry)
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("trust store is inaccessible: %w", err)
+	}
+	if len(content) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("trust store is corrupt: %w", err)
+	}
+	return entries, nil
+}
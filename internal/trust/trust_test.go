@@ -0,0 +1,81 @@
+package trust
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_LookupAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "tm", "trust.json")
+	store := NewStore(path)
+
+	if _, ok := store.Lookup("/src/api/.tm.yaml", "abc"); ok {
+		t.Fatal("expected unknown file before saving")
+	}
+
+	if err := store.Save("/src/api/.tm.yaml", "abc", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Save("/src/web/.tm.yaml", "def", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		hash        string
+		wantTrusted bool
+		wantOk      bool
+	}{
+		{name: "trusted file", path: "/src/api/.tm.yaml", hash: "abc", wantTrusted: true, wantOk: true},
+		{name: "denied file", path: "/src/web/.tm.yaml", hash: "def", wantTrusted: false, wantOk: true},
+		{name: "changed file", path: "/src/api/.tm.yaml", hash: "changed", wantOk: false},
+		{name: "unknown file", path: "/src/other/.tm.yaml", hash: "abc", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A fresh store reads the answers back from disk.
+			trusted, ok := NewStore(path).Lookup(tt.path, tt.hash)
+			if ok != tt.wantOk {
+				t.Errorf("Lookup() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if trusted != tt.wantTrusted {
+				t.Errorf("Lookup() trusted = %v, want %v", trusted, tt.wantTrusted)
+			}
+		})
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("expected temporary file to be renamed away")
+	}
+}
+
+func TestStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trust.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(path)
+
+	if _, ok := store.Lookup("/src/api/.tm.yaml", "abc"); ok {
+		t.Error("expected corrupt store to report unknown")
+	}
+	err := store.Save("/src/api/.tm.yaml", "abc", true)
+	if err == nil || !strings.Contains(err.Error(), "trust store is corrupt") {
+		t.Errorf("expected corrupt store error, got %v", err)
+	}
+}
+
+func TestStore_SaveMkdirError(t *testing.T) {
+	blockingFile := filepath.Join(t.TempDir(), "blocking")
+	if err := os.WriteFile(blockingFile, []byte(""), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(filepath.Join(blockingFile, "trust.json"))
+	if err := store.Save("/src/api/.tm.yaml", "abc", true); err == nil {
+		t.Fatal("expected error")
+	}
+}
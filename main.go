@@ -3,13 +3,23 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime/debug"
+	"slices"
+	"strings"
 
 	"github.com/griggsjared/tm/internal/app"
+	"github.com/griggsjared/tm/internal/cli"
 	"github.com/griggsjared/tm/internal/config"
 	"github.com/griggsjared/tm/internal/fzf"
+	"github.com/griggsjared/tm/internal/history"
+	"github.com/griggsjared/tm/internal/hook"
+	"github.com/griggsjared/tm/internal/picker"
+	"github.com/griggsjared/tm/internal/preview"
 	"github.com/griggsjared/tm/internal/session"
 	"github.com/griggsjared/tm/internal/tmux"
+	"github.com/griggsjared/tm/internal/trust"
 )
 
 var version = "dev"
@@ -19,24 +29,176 @@ func main() {
 }
 
 func run() int {
-	cmd := parseCommand(os.Args[1:])
+	cmd, err := cli.Parse(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		fmt.Fprintln(os.Stderr, "Run \"tm help\" for usage.")
+		return 2
+	}
+
+	if cmd.Name == "help" {
+		if err := cli.PrintHelp(os.Stdout, cmd.Args); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 2
+		}
+		return 0
+	}
+
+	if cmd.Name == "config" {
+		return runConfig(cmd)
+	}
 
-	cfg, err := config.Load()
+	cfg, err := config.Load(cmd.Config)
 	if err != nil {
 		fmt.Println("Error loading config:", err)
 		return 1
 	}
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
 
-	tmuxClient := tmux.NewClient(tmux.NewRunner(), cfg.TmuxPath)
+	socket := tmuxSocket(cmd, cfg)
+	preDefinedSessions := normalizeSockets(cfg.PreDefinedSessions, socket)
+	servers := tmuxServers(cmd, preDefinedSessions, socket)
+	tmuxClient := tmux.NewClient(tmux.NewRunner(), cfg.TmuxPath, socket, servers)
 	fzfClient := fzf.NewClient(fzf.NewRunner(), cfg.FzfPath)
-	sessionFinder := session.NewFinder(tmuxClient, cfg.PreDefinedSessions, cfg.SmartDirectories)
+	var sessionPicker app.Picker = fzfClient
+	if cfg.Picker == config.PickerBuiltin || (cfg.Picker == config.PickerAuto && !fzfClient.IsAvailable()) {
+		sessionPicker = picker.NewClient(picker.NewRunner())
+	}
+	sessionFinder := session.NewFinder(tmuxClient, preDefinedSessions, cfg.SmartDirectories, config.NewProjectLoader())
+	hookClient := hook.NewClient(hook.NewRunner())
+	trustStore := trust.NewStore(filepath.Join(cfg.StateDir, "trust.json"))
+	historyStore := history.NewStore(filepath.Join(cfg.StateDir, "history.json"))
+	configFile := config.NewFile(cfg.ConfigPath)
+	previewer := preview.NewClient(preview.NewRunner(), lookPath("git"), lookPath("eza"), lookPath("bat", "batcat"))
+
+	matcher := app.Matcher{Ranked: cfg.Match.Strategy == config.MatchRanked, Dirs: cfg.Match.Dirs}
+	popup := app.Popup{Width: cfg.Popup.Width, Height: cfg.Popup.Height, Border: cfg.Popup.Border, Always: cfg.Popup.Always}
 
-	return app.New(tmuxClient, fzfClient, sessionFinder, cfg.Debug, getVersion()).Run(cmd)
+	return app.New(tmuxClient, fzfClient, sessionPicker, sessionFinder, hookClient, trustStore, historyStore, configFile, previewer, app.Options{
+		Matcher:  matcher,
+		Popup:    popup,
+		CacheDir: cfg.CacheDir,
+		Debug:    cfg.Debug || cmd.Debug,
+		Version:  getVersion(),
+	}).Run(cmd)
 }
 
-func parseCommand(args []string) string {
-	if len(args) > 0 {
-		return args[0]
+// runConfig runs the config commands. They work on the config file itself,
+// so they run even when it cannot be loaded.
+func runConfig(cmd cli.Command) int {
+	if len(cmd.Args) == 0 || !slices.Contains([]string{"check", "show", "convert", "schema"}, cmd.Args[0]) {
+		fmt.Fprintln(os.Stderr, "Error: tm config needs a command: check, show, convert or schema")
+		return 2
+	}
+	if cmd.Args[0] == "convert" && cmd.String("to") == "" {
+		fmt.Fprintln(os.Stderr, "Error: tm config convert needs --to FORMAT")
+		return 2
+	}
+
+	if cmd.Args[0] == "schema" {
+		schema, err := config.Schema()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		os.Stdout.Write(schema)
+		return 0
+	}
+
+	path, err := config.Path(cmd.Config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	if cmd.Args[0] == "show" {
+		merged, err := config.Show(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		fmt.Print(merged)
+		return 0
+	}
+
+	if cmd.Args[0] == "convert" {
+		out, err := config.NewFile(path).Convert(cmd.String("to"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		os.Stdout.Write(out)
+		return 0
+	}
+
+	problems, err := config.NewFile(path).Check()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	fmt.Printf("%s: no problems found\n", path)
+	return 0
+}
+
+// tmuxSocket picks the tmux server from --socket-path, --socket-name or
+// TM_TMUX_SOCKET, in that order.
+func tmuxSocket(cmd cli.Command, cfg *config.Config) tmux.Socket {
+	switch {
+	case cmd.SocketPath != "":
+		return tmux.Socket{Path: cmd.SocketPath}
+	case cmd.SocketName != "":
+		return tmux.Socket{Name: cmd.SocketName}
+	}
+	return tmux.ParseSocket(cfg.TmuxSocket)
+}
+
+// normalizeSockets returns a copy of the pre-defined sessions in which
+// those on the main server have no socket, like every other session there.
+func normalizeSockets(pds []session.PreDefinedSession, socket tmux.Socket) []session.PreDefinedSession {
+	normalized := slices.Clone(pds)
+	for i, pd := range normalized {
+		if pd.Socket == socket.String() {
+			normalized[i].Socket = ""
+		}
+	}
+	return normalized
+}
+
+// tmuxServers returns the other servers to list sessions from: those of
+// pre-defined sessions and those given to --servers.
+func tmuxServers(cmd cli.Command, pds []session.PreDefinedSession, socket tmux.Socket) []string {
+	var servers []string
+	add := func(s string) {
+		if s != "" && s != socket.String() && !slices.Contains(servers, s) {
+			servers = append(servers, s)
+		}
+	}
+	for _, pd := range pds {
+		add(pd.Socket)
+	}
+	if list := cmd.String("servers"); list != "" {
+		for _, s := range strings.Split(list, ",") {
+			add(strings.TrimSpace(s))
+		}
+	}
+	return servers
+}
+
+// lookPath returns the path of the first of names found in PATH, or an empty
+// string when none is installed.
+func lookPath(names ...string) string {
+	for _, name := range names {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
 	}
 	return ""
 }